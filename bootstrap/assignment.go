@@ -0,0 +1,103 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// assignmentPollInterval is how often a pod checks for its neighbor assignment while waiting for the controller
+var assignmentPollInterval = 2 * time.Second
+
+// AssignmentConfigMapName returns the name of the ConfigMap the controller publishes a pod's assignment to,
+// distinct from the pod's own output so the two never contend for the same keys
+func AssignmentConfigMapName(podName string) string {
+	return podName + "-aeron-assignment"
+}
+
+// renderAssigned waits for the controller to assign our neighbors, then renders them like render
+func renderAssigned(ctx context.Context, opts Options) (Result, renderPlan, error) {
+	for {
+		currentPod, err := CurrentPod(ctx, opts)
+		if err != nil {
+			return Result{}, renderPlan{}, err
+		}
+
+		neighbors, found, err := readAssignment(ctx, opts, currentPod)
+		if err != nil {
+			return Result{}, renderPlan{pod: currentPod}, err
+		}
+		if found {
+			if len(neighbors) == 0 {
+				return Result{}, renderPlan{pod: currentPod}, ErrNoPeers
+			}
+			if currentPod, err = WaitForNetworkStatus(ctx, opts, currentPod); err != nil {
+				return Result{}, renderPlan{pod: currentPod}, err
+			}
+			return renderNeighbors(ctx, opts, currentPod, Result{Neighbors: neighbors})
+		}
+
+		slog.Info("Waiting for the controller to assign neighbors", LogKeyPod, currentPod.Name, LogKeyNamespace, opts.Namespace, "from", opts.Assignment)
+		select {
+		case <-ctx.Done():
+			return Result{}, renderPlan{pod: currentPod}, fmt.Errorf("%w: pod %s: %v", ErrNoAssignment, currentPod.Name, ctx.Err())
+		case <-time.After(assignmentPollInterval):
+		}
+	}
+}
+
+// readAssignment reads the neighbors the controller assigned to our own pod, reporting false if there are none yet
+func readAssignment(ctx context.Context, opts Options, pod v1.Pod) ([]PodInfo, bool, error) {
+	var membersJSON string
+	switch opts.Assignment {
+	case AssignmentAnnotation:
+		membersJSON = pod.Annotations[AssignmentAnnotationKey]
+	case AssignmentConfigMap:
+		name := AssignmentConfigMapName(pod.Name)
+		var configMap *v1.ConfigMap
+		err := callWithRetry(ctx, opts, "get_configmap", func(ctx context.Context) error {
+			var err error
+			configMap, err = opts.Clientset.CoreV1().ConfigMaps(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+			return err
+		})
+		if apierrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get assignment ConfigMap %s: %w", name, err)
+		}
+		membersJSON = configMap.Data[FabricMembersKey]
+	default:
+		return nil, false, fmt.Errorf("unknown assignment source %q, expected %s or %s", opts.Assignment, AssignmentConfigMap, AssignmentAnnotation)
+	}
+
+	if membersJSON == "" {
+		return nil, false, nil
+	}
+	neighbors, err := decodeMembers(membersJSON)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid neighbor assignment for pod %s: %w", pod.Name, err)
+	}
+	return neighbors, true, nil
+}
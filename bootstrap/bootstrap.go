@@ -0,0 +1,637 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+// Package bootstrap discovers Aeron media driver pods and writes the bootstrap properties file
+// that points a media driver's name resolver at its neighbors
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Defaults used by DefaultOptions
+const (
+	DefaultLabelSelector        = "aeron.io/media-driver=true"
+	DefaultBootstrapPath        = "/etc/aeron/bootstrap.properties"
+	DefaultDiscoveryPort        = 8050
+	DefaultHostnameSuffix       = ".aeron"
+	DefaultAPITimeout           = 10 * time.Second
+	DefaultNetworkStatusTimeout = time.Minute
+)
+
+// Attribute keys shared by every log message, so log pipelines can extract them reliably
+const (
+	LogKeyPod       = "pod"
+	LogKeyNamespace = "namespace"
+	LogKeyIP        = "ip"
+	LogKeyReason    = "reason"
+)
+
+// Per-pod overrides, read from each candidate pod and from our own pod
+const (
+	ResolverPortAnnotation    = "aeron.io/resolver-port"
+	ResolverNameAnnotation    = "aeron.io/resolver-name"
+	ResolverAddressAnnotation = "aeron.io/resolver-address"
+)
+
+// The identity each bootstrapping pod publishes for peers to consume, kept apart from the overrides
+// so a published value never overrides what a later refresh works out
+const (
+	PublishedPortAnnotation    = "aeron.io/published-port"
+	PublishedNameAnnotation    = "aeron.io/published-name"
+	PublishedAddressAnnotation = "aeron.io/published-address"
+)
+
+// Per-pod choice of the Multus network its resolver address is taken from, overriding Options.SecondaryInterface
+const (
+	NetworkAnnotation          = "aeron.io/network"
+	NetworkInterfaceAnnotation = "aeron.io/network-interface"
+)
+
+// Options configures discovery and the bootstrap file written by Run
+type Options struct {
+	// Clientset is used for every Kubernetes API call
+	Clientset kubernetes.Interface
+	// Namespace is searched for media driver pods, and holds our own pod
+	Namespace string
+	// PodName is the name of our own pod, usually its hostname
+	PodName string
+	// LabelSelector selects the media driver pods
+	LabelSelector string
+	// MaxPods limits the neighbors to the oldest pods, or the lowest ordinals with Ordinal set, 0 means unlimited
+	MaxPods int
+	// Ordinal, if set, bootstraps our own StatefulSet member against others by ordinal instead of by age,
+	// one of the Ordinal constants
+	Ordinal string
+	// ClusterDomain is the cluster DNS domain of predicted StatefulSet members, defaulting to DefaultClusterDomain
+	ClusterDomain string
+	// BootstrapPath is where the bootstrap properties file is written
+	BootstrapPath string
+	// Output selects where Run writes the properties, one of the Output constants, empty meaning OutputFile
+	Output string
+	// OutputName names the ConfigMap or Secret written by Run, defaulting to <pod>-aeron-bootstrap
+	OutputName string
+	// Lookup, if set, also writes a lookup of every driver's resolver name to its address, in one of the Lookup formats,
+	// beside the bootstrap file or under LookupKey in the ConfigMap or Secret
+	Lookup string
+	// LookupPath is where the lookup file is written, defaulting to aeron-names.<format> beside the bootstrap file
+	LookupPath string
+	// Assignment, if set, makes Run wait for the neighbors a controller assigned to our own pod instead of discovering them,
+	// one of the Assignment constants
+	Assignment string
+	// ForceConflicts takes over ConfigMap or Secret fields owned by another field manager instead of failing
+	ForceConflicts bool
+	// DiscoveryPort is the resolver port used for pods that don't advertise their own
+	DiscoveryPort int
+	// HostnameSuffix is appended to <pod>.<namespace>, or <node> with host network node naming, to build resolver names
+	HostnameSuffix string
+	// ResolverNameTemplate, if set, builds resolver names instead, executed against ResolverNameData
+	// It is parsed once, by ParseResolverNameTemplate, rather than for every pod
+	ResolverNameTemplate *template.Template
+	// ClusterName is available to ResolverNameTemplate as .Cluster
+	ClusterName string
+	// SecondaryInterface selects the Multus network each pod's address is taken from
+	SecondaryInterface SecondaryInterface
+	// HostNetwork selects the address and resolver name of pods using host networking
+	HostNetwork HostNetwork
+	// EmitEvents records the bootstrap result as Events against our own pod
+	EmitEvents bool
+	// AnnotatePod records the chosen neighbors as an annotation on our own pod
+	AnnotatePod bool
+	// PublishIdentity publishes our resolver address, name and port as annotations on our own pod
+	PublishIdentity bool
+	// APITimeout bounds each individual Kubernetes API call, retries are bounded by ctx
+	APITimeout time.Duration
+	// NetworkStatusTimeout is how long Run waits for the Multus networks our own pod requests to appear in its
+	// network-status, 0 to fail immediately
+	NetworkStatusTimeout time.Duration
+	// Metrics, if set, records discovery, API error and write metrics
+	Metrics *Metrics
+}
+
+// DefaultOptions returns the options used when nothing is configured
+func DefaultOptions() Options {
+	return Options{
+		LabelSelector:        DefaultLabelSelector,
+		BootstrapPath:        DefaultBootstrapPath,
+		Output:               OutputFile,
+		DiscoveryPort:        DefaultDiscoveryPort,
+		HostnameSuffix:       DefaultHostnameSuffix,
+		EmitEvents:           true,
+		PublishIdentity:      true,
+		APITimeout:           DefaultAPITimeout,
+		NetworkStatusTimeout: DefaultNetworkStatusTimeout,
+	}
+}
+
+// PodInfo holds information about a media driver pod
+type PodInfo struct {
+	Name      string
+	Namespace string
+	// IP is the pod's address, or a predicted StatefulSet member's DNS name
+	IP           string
+	Port         int
+	ResolverName string
+	IPSource     string
+	CreationTime time.Time
+}
+
+// PodSkip records why a candidate pod was not used as a bootstrap neighbor
+type PodSkip struct {
+	Name      string
+	Namespace string
+	Reason    string
+	Detail    string
+}
+
+// Key returns namespace/name identifying the pod across namespaces, or just its name if it has no namespace
+func (p PodInfo) Key() string {
+	if p.Namespace == "" {
+		return p.Name
+	}
+	return p.Namespace + "/" + p.Name
+}
+
+// Endpoint returns the ip:port pair used to bootstrap against this pod
+func (p PodInfo) Endpoint() string {
+	return hostPort(p.IP, p.Port)
+}
+
+// hostPort joins an address and port, bracketing IPv6 addresses
+func hostPort(ip string, port int) string {
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
+
+// Result summarises the decisions taken while bootstrapping
+type Result struct {
+	Neighbors []PodInfo
+	// Peers are every eligible media driver discovered, before Neighbors were chosen from them, and are listed by Lookup
+	// They are only set by discovery, and otherwise found again when a lookup is rendered
+	Peers             []PodInfo
+	Skipped           []PodSkip
+	ResolverName      string
+	ResolverInterface string
+	SelfIPSource      string
+	// Properties is the rendered bootstrap properties file content
+	Properties string
+	// Lookup is the rendered lookup file content, if Options.Lookup is set
+	Lookup string
+	// Written is false when the bootstrap file was already up to date, or Render was used
+	Written bool
+}
+
+// DiscoverPods finds all media driver pods with IP addresses, sorted by age, limited to opts.MaxPods,
+// additionally returning the pods skipped by Multus or resolver name validation
+func DiscoverPods(ctx context.Context, opts Options) ([]PodInfo, []PodSkip, error) {
+	eligible, skipped, err := discoverEligiblePods(ctx, opts)
+	if err != nil || len(eligible) == 0 {
+		return nil, skipped, err
+	}
+	return selectNeighbors(opts, eligible), skipped, nil
+}
+
+// selectNeighbors returns the oldest opts.MaxPods of the eligible pods, which are already sorted by age
+func selectNeighbors(opts Options, eligible []PodInfo) []PodInfo {
+	neighbors := oldestPods(slices.Clone(eligible), opts.MaxPods)
+
+	slog.Info("Found media driver pods with IP addresses", LogKeyNamespace, opts.Namespace, "count", len(neighbors))
+	for _, pod := range neighbors {
+		slog.Info("Selected bootstrap neighbor", LogKeyPod, pod.Name, LogKeyNamespace, opts.Namespace, LogKeyIP, pod.IP, "endpoint", pod.Endpoint())
+	}
+	return neighbors
+}
+
+// discoverEligiblePods finds every media driver pod with an IP address, sorted by age but not limited to opts.MaxPods,
+// additionally returning the pods skipped by validation
+func discoverEligiblePods(ctx context.Context, opts Options) ([]PodInfo, []PodSkip, error) {
+	namespace := opts.Namespace
+	pods, err := listCandidatePods(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var runningPods []PodInfo
+	var skipped []PodSkip
+
+	nodes := nodeCache{}
+	for _, pod := range pods {
+		podInfo, skip, err := evaluatePod(ctx, pod, opts, nodes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if skip != nil {
+			logSkip(pod, skip)
+			skipped = append(skipped, *skip)
+			continue
+		}
+
+		// Only filter on IP address - include all pods with IPs regardless of status
+		if podInfo.IP != "" {
+			runningPods = append(runningPods, podInfo)
+			slog.Info("Found media driver pod", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, LogKeyIP, podInfo.IP,
+				"source", podInfo.IPSource, "phase", pod.Status.Phase, "created", pod.CreationTimestamp.Time)
+		}
+	}
+
+	if len(runningPods) == 0 {
+		slog.Warn("No media driver pods with IP addresses found", LogKeyNamespace, namespace)
+		return nil, skipped, nil
+	}
+
+	return oldestPods(runningPods, 0), skipped, nil
+}
+
+// oldestPods sorts pods by creation timestamp from oldest to newest, keeping at most maxPods (0 means unlimited)
+func oldestPods(pods []PodInfo, maxPods int) []PodInfo {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].CreationTime.Before(pods[j].CreationTime)
+	})
+
+	if maxPods > 0 && len(pods) > maxPods {
+		pods = pods[:maxPods]
+		slog.Info("Limited to oldest pods", "max", maxPods)
+	}
+	return pods
+}
+
+// listCandidatePods lists the pods matching opts.LabelSelector in opts.Namespace
+func listCandidatePods(ctx context.Context, opts Options) ([]v1.Pod, error) {
+	slog.Info("Searching for media driver pods", LogKeyNamespace, opts.Namespace, "selector", opts.LabelSelector)
+
+	// List pods with the media driver label
+	listOptions := metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
+	}
+
+	var pods *v1.PodList
+	err := callWithRetry(ctx, opts, "list_pods", func(ctx context.Context) error {
+		var err error
+		pods, err = opts.Clientset.CoreV1().Pods(opts.Namespace).List(ctx, listOptions)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return pods.Items, nil
+}
+
+// evaluatePod validates a candidate pod's Multus status and works out the address peers bootstrap against,
+// returning why it was skipped instead if it fails validation
+// The returned PodInfo has no IP if the pod has no address yet
+// Nodes of host-network pods are looked up once each through nodes
+func evaluatePod(ctx context.Context, pod v1.Pod, opts Options, nodes nodeCache) (PodInfo, *PodSkip, error) {
+	// Validate Multus network configuration if present
+	if skip := CheckMultusNetworkStatus(pod); skip != nil {
+		return PodInfo{}, skip, nil
+	}
+
+	// prefer the address the pod published for itself, so every peer agrees with its own choice
+	// otherwise get secondary interface IP if available, or the Node's address with host networking
+	// fallback to primary PodIP if secondary is not found
+	ip, source := PublishedIP(pod)
+	if ip == "" {
+		var err error
+		ip, source, err = podAddress(ctx, opts, nodes, pod)
+		if err != nil {
+			return PodInfo{}, nil, fmt.Errorf("failed to get IP for pod %s: %v", pod.Name, err)
+		}
+	}
+
+	resolverName := strings.TrimSpace(pod.Annotations[PublishedNameAnnotation])
+	if resolverName == "" {
+		var err error
+		resolverName, err = podResolverName(pod, pod.Name, pod.Namespace, opts)
+		if err != nil {
+			return PodInfo{}, &PodSkip{Name: pod.Name, Namespace: pod.Namespace, Reason: SkipReasonInvalidResolverName, Detail: err.Error()}, nil
+		}
+	}
+
+	return PodInfo{
+		Name:         pod.Name,
+		Namespace:    pod.Namespace,
+		IP:           ip,
+		Port:         getAnnotatedPort(pod, PublishedPortAnnotation, getPodResolverPort(pod, opts.DiscoveryPort)),
+		ResolverName: resolverName,
+		IPSource:     source,
+		CreationTime: pod.CreationTimestamp.Time,
+	}, nil, nil
+}
+
+// CurrentPod retrieves our own pod object, opts.PodName in opts.Namespace, from the Kubernetes API
+func CurrentPod(ctx context.Context, opts Options) (v1.Pod, error) {
+	var pod *v1.Pod
+	err := callWithRetry(ctx, opts, "get_pod", func(ctx context.Context) error {
+		var err error
+		pod, err = opts.Clientset.CoreV1().Pods(opts.Namespace).Get(ctx, opts.PodName, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return v1.Pod{}, fmt.Errorf("%w: pod %s in namespace %s", ErrSelfNotFound, opts.PodName, opts.Namespace)
+	}
+	if err != nil {
+		return v1.Pod{}, fmt.Errorf("failed to get current pod %s in namespace %s: %w", opts.PodName, opts.Namespace, err)
+	}
+	return *pod, nil
+}
+
+// getPodResolverPort returns the resolver port a pod advertises via the aeron.io/resolver-port annotation,
+// or defaultPort if the annotation is absent or invalid
+func getPodResolverPort(pod v1.Pod, defaultPort int) int {
+	return getAnnotatedPort(pod, ResolverPortAnnotation, defaultPort)
+}
+
+// getAnnotatedPort returns the port in the given annotation of a pod, or defaultPort if it is absent or invalid
+func getAnnotatedPort(pod v1.Pod, annotation string, defaultPort int) int {
+	portStr, ok := pod.Annotations[annotation]
+	if !ok {
+		return defaultPort
+	}
+	if port, err := strconv.Atoi(strings.TrimSpace(portStr)); err == nil && port > 0 && port <= 65535 {
+		return port
+	}
+	slog.Warn("Invalid annotation, using default port", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+		"annotation", annotation, "value", portStr, "default", defaultPort)
+	return defaultPort
+}
+
+// getPodResolverName returns the resolver name a pod advertises via the aeron.io/resolver-name annotation,
+// or defaultName if the annotation is absent
+func getPodResolverName(pod v1.Pod, defaultName string) string {
+	if name := strings.TrimSpace(pod.Annotations[ResolverNameAnnotation]); name != "" {
+		return name
+	}
+	return defaultName
+}
+
+// buildAeronHostname creates the full Aeron hostname with namespace and suffix
+func buildAeronHostname(podName, namespace, suffix string) string {
+	return fmt.Sprintf("%s.%s%s", podName, namespace, suffix)
+}
+
+// createBootstrapPropertiesInDir creates the bootstrap properties file in a specified directory (for testing)
+func createBootstrapPropertiesInDir(dir string, neighborIPs []string, discoveryPort int, fullHostname, shortHostname string) error {
+	filePath := filepath.Join(dir, "bootstrap.properties")
+	return createBootstrapPropertiesAtPath(dir, filePath, neighborIPs, discoveryPort, fullHostname, shortHostname)
+}
+
+// createBootstrapPropertiesAtPath creates the bootstrap properties file at a specified path,
+// using the same discovery port for every neighbor
+func createBootstrapPropertiesAtPath(dir, filePath string, neighborIPs []string, discoveryPort int, fullHostname, resolverInterface string) error {
+	var neighbors []string
+	for _, ip := range neighborIPs {
+		neighbors = append(neighbors, hostPort(ip, discoveryPort))
+	}
+	return createBootstrapPropertiesWithEndpoints(dir, filePath, neighbors, discoveryPort, fullHostname, resolverInterface)
+}
+
+// createBootstrapPropertiesWithEndpoints creates the bootstrap properties file at a specified path
+// from pre-built neighbor ip:port endpoints, binding the local resolver to resolverInterface:discoveryPort
+func createBootstrapPropertiesWithEndpoints(dir, filePath string, neighbors []string, discoveryPort int, fullHostname, resolverInterface string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+
+	content := RenderProperties(neighbors, discoveryPort, fullHostname, resolverInterface)
+
+	// Write the file
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write bootstrap properties file: %v", err)
+	}
+
+	if len(neighbors) > 0 {
+		slog.Info("Created bootstrap properties file", "path", filePath, "neighbors", strings.Join(neighbors, ","),
+			"name", fullHostname, LogKeyIP, resolverInterface, "port", discoveryPort)
+	} else {
+		slog.Info("Created bootstrap properties file (no neighbors found)", "path", filePath,
+			"name", fullHostname, LogKeyIP, resolverInterface, "port", discoveryPort)
+	}
+
+	return nil
+}
+
+// RenderProperties renders the bootstrap properties file content for the given neighbor ip:port endpoints,
+// binding the local resolver named fullHostname to resolverInterface:discoveryPort
+func RenderProperties(neighbors []string, discoveryPort int, fullHostname, resolverInterface string) string {
+	// Create the properties content with resolver configuration
+	var contentLines []string
+	if len(neighbors) > 0 {
+		contentLines = append(contentLines, fmt.Sprintf("%s=%s", NeighborKey, strings.Join(neighbors, ",")))
+	}
+	contentLines = append(contentLines, "aeron.name.resolver.supplier=driver")
+
+	contentLines = append(contentLines, fmt.Sprintf("aeron.driver.resolver.name=%s", fullHostname))
+	contentLines = append(contentLines, fmt.Sprintf("aeron.driver.resolver.interface=%s", hostPort(resolverInterface, discoveryPort)))
+	return strings.Join(contentLines, "\n") + "\n"
+}
+
+// publishResult records the bootstrap result as Events and, optionally, a pod annotation
+// Failures are logged but never fatal, as the bootstrap file is what the media driver depends on
+func publishResult(ctx context.Context, opts Options, pod v1.Pod, result Result) {
+	if opts.EmitEvents {
+		if err := recordBootstrapEvents(ctx, opts, pod, result); err != nil {
+			slog.Warn("Failed to publish bootstrap report", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, "error", err)
+		}
+	}
+	if opts.AnnotatePod {
+		if err := annotateBootstrapResult(ctx, opts, pod, result); err != nil {
+			slog.Warn("Failed to publish bootstrap report", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, "error", err)
+		}
+	}
+}
+
+// Render looks up our own pod and discovers its neighbors, or waits for those assigned with opts.Assignment set,
+// rendering the bootstrap properties without writing the file or recording anything against the pod
+func Render(ctx context.Context, opts Options) (Result, error) {
+	result, _, err := render(ctx, opts)
+	return result, err
+}
+
+// renderPlan holds what Run needs to write the file and publish the resolver identity
+type renderPlan struct {
+	pod               v1.Pod
+	neighbors         []string
+	discoveryPort     int
+	resolverInterface string
+}
+
+// render behaves like Render, additionally returning what Run needs to write the rendered file
+func render(ctx context.Context, opts Options) (Result, renderPlan, error) {
+	if opts.Clientset == nil {
+		return Result{}, renderPlan{}, errors.New("no Kubernetes clientset configured")
+	}
+	if opts.Assignment != "" {
+		return renderAssigned(ctx, opts)
+	}
+
+	// Look up our own pod, which events and annotations are recorded against
+	currentPod, err := CurrentPod(ctx, opts)
+	if err != nil {
+		return Result{}, renderPlan{}, err
+	}
+	// Wait for our own Multus networks before discovery, so the peers found are as fresh as our address
+	currentPod, err = WaitForNetworkStatus(ctx, opts, currentPod)
+	if err != nil {
+		return Result{}, renderPlan{pod: currentPod}, err
+	}
+
+	plan := renderPlan{pod: currentPod}
+
+	// Find all media driver pods, or our StatefulSet members by ordinal
+	var pods []PodInfo
+	var skipped []PodSkip
+	var eligible []PodInfo
+	if opts.Ordinal != "" {
+		pods, skipped, err = DiscoverOrdinalPeers(ctx, opts, currentPod)
+	} else {
+		eligible, skipped, err = discoverEligiblePods(ctx, opts)
+		if len(eligible) > 0 {
+			pods = selectNeighbors(opts, eligible)
+		}
+	}
+	if err != nil {
+		return Result{}, plan, fmt.Errorf("error finding media driver pods: %w", err)
+	}
+	opts.Metrics.observeDiscovery(pods, skipped)
+
+	result := Result{Neighbors: pods, Peers: eligible, Skipped: skipped}
+
+	if len(pods) == 0 {
+		return result, plan, ErrNoPeers
+	}
+
+	return renderNeighbors(ctx, opts, currentPod, result)
+}
+
+// renderNeighbors renders the bootstrap properties for our own pod against the neighbors in result
+func renderNeighbors(ctx context.Context, opts Options, currentPod v1.Pod, result Result) (Result, renderPlan, error) {
+	plan := renderPlan{pod: currentPod}
+	pods := result.Neighbors
+
+	// Extract endpoints from pods (already sorted oldest to newest), each using the peer's own resolver port
+	var neighbors []string
+	for _, pod := range pods {
+		neighbors = append(neighbors, pod.Endpoint())
+	}
+
+	// Our own pod must not silently fall back to its primary interface while its Multus networks are pending
+	if skip := CheckMultusNetworkStatus(currentPod); skip != nil {
+		return result, plan, fmt.Errorf("%w: pod %s %s", ErrMultusNotReady, currentPod.Name, skip.Detail)
+	}
+
+	// Determine resolver interface IP from current pod, binding the address peers are told to reach us at if overridden
+	resolverInterface, ipSource := annotatedIP(currentPod, ResolverAddressAnnotation), IPSourcePublished
+	if resolverInterface == "" {
+		var err error
+		resolverInterface, ipSource, err = podAddress(ctx, opts, nodeCache{}, currentPod)
+		if err != nil {
+			return result, plan, fmt.Errorf("failed to get current pod IP for resolver interface: %w", err)
+		}
+	}
+	if resolverInterface == "" {
+		return result, plan, fmt.Errorf("current pod %s has no IP address for resolver interface", currentPod.Name)
+	}
+
+	// Get configuration, allowing our own pod's annotations to override the port and resolver name
+	discoveryPort := getPodResolverPort(currentPod, opts.DiscoveryPort)
+	aeronHostname, err := podResolverName(currentPod, opts.PodName, opts.Namespace, opts)
+	if err != nil {
+		return result, plan, fmt.Errorf("failed to name current pod %s for its resolver: %w", currentPod.Name, err)
+	}
+
+	result.ResolverName = aeronHostname
+	result.ResolverInterface = hostPort(resolverInterface, discoveryPort)
+	result.SelfIPSource = ipSource
+	result.Properties = RenderProperties(neighbors, discoveryPort, aeronHostname, resolverInterface)
+	if opts.Lookup != "" {
+		// Ordinal and assigned neighbors are only some of the drivers, so the rest are found for the lookup
+		if result.Peers == nil {
+			if result.Peers, _, err = discoverEligiblePods(ctx, opts); err != nil {
+				return result, plan, fmt.Errorf("error finding media driver pods for the lookup: %w", err)
+			}
+		}
+		// Our own entry comes first, so it wins over a neighbor of the same name, and neighbors over the other peers,
+		// as predicted members are only among the neighbors
+		self := PodInfo{Name: currentPod.Name, Namespace: currentPod.Namespace, IP: resolverInterface, Port: discoveryPort, ResolverName: aeronHostname}
+		entries := append(append([]PodInfo{self}, pods...), result.Peers...)
+		result.Lookup = RenderLookup(opts.Lookup, entries)
+	}
+	plan.neighbors = neighbors
+	plan.discoveryPort = discoveryPort
+	plan.resolverInterface = resolverInterface
+	return result, plan, nil
+}
+
+// Run looks up our own pod, discovers its neighbors and writes the bootstrap properties file if its content has changed
+// With opts.Assignment set, it waits for the neighbors assigned by the controller instead of discovering them
+func Run(ctx context.Context, opts Options) (Result, error) {
+	result, plan, err := render(ctx, opts)
+	currentPod := plan.pod
+	// An empty assignment is the controller's decision, so it is not reported like a failed discovery
+	if errors.Is(err, ErrNoPeers) && opts.Assignment == "" {
+		// Only report the first failure, a refresh keeps the previously written file
+		if !outputExists(ctx, opts, currentPod) {
+			publishResult(ctx, opts, currentPod, result)
+		}
+		return result, err
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return writeResult(ctx, opts, plan, result)
+}
+
+// writeResult writes the rendered result to the configured output and, if it changed, records it against our own pod
+func writeResult(ctx context.Context, opts Options, plan renderPlan, result Result) (Result, error) {
+	currentPod := plan.pod
+	written, err := writeOutput(ctx, opts, plan, result)
+	if err != nil {
+		return result, err
+	}
+	opts.Metrics.observeWrite(written)
+	result.Written = written
+
+	// Publish whenever the pod doesn't already carry our identity, even with the output unchanged, e.g. after a failed publish
+	if opts.PublishIdentity && (written || !identityPublished(currentPod, plan.resolverInterface, result.ResolverName, plan.discoveryPort)) {
+		if err := publishResolverIdentity(ctx, opts, currentPod, plan.resolverInterface, result.ResolverName, plan.discoveryPort); err != nil {
+			slog.Warn("Failed to publish resolver identity", LogKeyPod, currentPod.Name, LogKeyNamespace, opts.Namespace, "error", err)
+		}
+	}
+
+	if !written {
+		return result, nil
+	}
+	publishResult(ctx, opts, currentPod, result)
+
+	return result, nil
+}
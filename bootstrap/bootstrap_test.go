@@ -14,7 +14,7 @@
 // You should have received a copy of the GNU Affero General Public License
 // along with this program.  If not, see <https://www.gnu.org/licenses/>.
 
-package main
+package bootstrap
 
 import (
 	"context"
@@ -27,10 +27,11 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
-func TestGetMediaDriverPodsWithSecondaryInterface(t *testing.T) {
+func TestDiscoverPodsWithSecondaryInterface(t *testing.T) {
 	tests := []struct {
 		name          string
 		pods          []corev1.Pod
@@ -57,7 +58,7 @@ func TestGetMediaDriverPodsWithSecondaryInterface(t *testing.T) {
 			},
 		},
 		{
-			name: "pod with a secondary interface and network env var",
+			name: "pod with a secondary interface and network name",
 			pods: []corev1.Pod{
 				createTestPodWithSecondaryInterface("aeron-0", "10.0.0.1", "10.0.0.2", "Running", "custom-network", "", time.Now().Add(-2*time.Minute)),
 			},
@@ -67,7 +68,7 @@ func TestGetMediaDriverPodsWithSecondaryInterface(t *testing.T) {
 			networkName: "custom-network",
 		},
 		{
-			name: "pod with a secondary interface and interface name env var",
+			name: "pod with a secondary interface and interface name",
 			pods: []corev1.Pod{
 				createTestPodWithSecondaryInterface("aeron-0", "10.0.0.1", "10.0.0.2", "Running", "", "custom1", time.Now().Add(-2*time.Minute)),
 			},
@@ -77,7 +78,7 @@ func TestGetMediaDriverPodsWithSecondaryInterface(t *testing.T) {
 			interfaceName: "custom1",
 		},
 		{
-			name: "pod with a secondary interface and interface and network env vars",
+			name: "pod with a secondary interface and interface and network names",
 			pods: []corev1.Pod{
 				createTestPodWithSecondaryInterface("aeron-0", "10.0.0.1", "10.0.0.2", "Running", "custom-network", "custom1", time.Now().Add(-2*time.Minute)),
 			},
@@ -101,23 +102,15 @@ func TestGetMediaDriverPodsWithSecondaryInterface(t *testing.T) {
 				}
 			}
 
-			os.Unsetenv("AERON_MD_SECONDARY_INTERFACE_NETWORK_NAME")
-			os.Unsetenv("AERON_MD_SECONDARY_INTERFACE_NAME")
-			if tt.networkName != "" {
-				// Set the environment variable for secondary interface network name
-				t.Setenv("AERON_MD_SECONDARY_INTERFACE_NETWORK_NAME", tt.networkName)
-			}
-			if tt.interfaceName != "" {
-				// Set the environment variable for secondary interface name
-				t.Setenv("AERON_MD_SECONDARY_INTERFACE_NAME", tt.interfaceName)
-			}
-			result, err := getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+			opts := testOptions(clientset)
+			opts.SecondaryInterface = SecondaryInterface{NetworkName: tt.networkName, InterfaceName: tt.interfaceName}
+			result, _, err := DiscoverPods(context.TODO(), opts)
 			if err != nil {
-				t.Fatalf("getMediaDriverPods() error = %v", err)
+				t.Fatalf("DiscoverPods() error = %v", err)
 			}
 
 			if len(result) != len(tt.expected) {
-				t.Errorf("getMediaDriverPods() returned %d pods, expected %d", len(result), len(tt.expected))
+				t.Errorf("DiscoverPods() returned %d pods, expected %d", len(result), len(tt.expected))
 				return
 			}
 
@@ -128,7 +121,7 @@ func TestGetMediaDriverPodsWithSecondaryInterface(t *testing.T) {
 	}
 }
 
-func TestGetMediaDriverPods(t *testing.T) {
+func TestDiscoverPods(t *testing.T) {
 	tests := []struct {
 		name     string
 		pods     []corev1.Pod
@@ -196,13 +189,13 @@ func TestGetMediaDriverPods(t *testing.T) {
 				}
 			}
 
-			result, err := getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+			result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
 			if err != nil {
-				t.Fatalf("getMediaDriverPods() error = %v", err)
+				t.Fatalf("DiscoverPods() error = %v", err)
 			}
 
 			if len(result) != len(tt.expected) {
-				t.Errorf("getMediaDriverPods() returned %d pods, expected %d", len(result), len(tt.expected))
+				t.Errorf("DiscoverPods() returned %d pods, expected %d", len(result), len(tt.expected))
 				return
 			}
 
@@ -270,9 +263,6 @@ func TestCreateBootstrapProperties(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			// Set the hostname for the test
-			t.Setenv("HOSTNAME", tt.hostname)
-
 			// Create temporary directory for test
 			tempDir, err := os.MkdirTemp("", "aeron-test-")
 			if err != nil {
@@ -354,10 +344,6 @@ func TestCreateBootstrapPropertiesWithNamespaceHostname(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			// Set test env values
-			t.Setenv("HOSTNAME", tt.podHostname)
-			t.Setenv("AERON_MD_HOSTNAME_SUFFIX", tt.suffix)
-
 			// Create temporary directory for test
 			tempDir, err := os.MkdirTemp("", "aeron-hostname-test-")
 			if err != nil {
@@ -369,7 +355,7 @@ func TestCreateBootstrapPropertiesWithNamespaceHostname(t *testing.T) {
 			aeronDir := filepath.Join(tempDir, "aeron")
 
 			// Build the full hostname with namespace
-			fullHostname := buildAeronHostname(tt.namespace)
+			fullHostname := buildAeronHostname(tt.podHostname, tt.namespace, tt.suffix)
 
 			err = createBootstrapPropertiesInDir(aeronDir, tt.neighborIPs, tt.discoveryPort, fullHostname, tt.podHostname)
 			if err != nil {
@@ -399,97 +385,7 @@ func TestCreateBootstrapPropertiesWithNamespaceHostname(t *testing.T) {
 	}
 }
 
-func TestGetDiscoveryPort(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected int
-	}{
-		{
-			name:     "default port when env not set",
-			envValue: "",
-			expected: 8050,
-		},
-		{
-			name:     "valid env port",
-			envValue: "9090",
-			expected: 9090,
-		},
-		{
-			name:     "invalid env port - non-numeric",
-			envValue: "invalid",
-			expected: 8050,
-		},
-		{
-			name:     "invalid env port - out of range",
-			envValue: "99999",
-			expected: 8050,
-		},
-		{
-			name:     "invalid env port - zero",
-			envValue: "0",
-			expected: 8050,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("AERON_MD_DISCOVERY_PORT", tt.envValue)
-			} else {
-				os.Unsetenv("AERON_MD_DISCOVERY_PORT")
-			}
-
-			result := getDiscoveryPort()
-			if result != tt.expected {
-				t.Errorf("getDiscoveryPort() = %d, expected %d", result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestGetLabelSelector(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected string
-	}{
-		{
-			name:     "default label when env not set",
-			envValue: "",
-			expected: "aeron.io/media-driver=true",
-		},
-		{
-			name:     "custom label from environment",
-			envValue: "app=aeron,version=1.0",
-			expected: "app=aeron,version=1.0",
-		},
-		{
-			name:     "single custom label",
-			envValue: "service=media-driver",
-			expected: "service=media-driver",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("AERON_MD_LABEL_SELECTOR", tt.envValue)
-			} else {
-				os.Unsetenv("AERON_MD_LABEL_SELECTOR")
-			}
-
-			result := getLabelSelector()
-			if result != tt.expected {
-				t.Errorf("getLabelSelector() = %s, expected %s", result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestGetMediaDriverPodsWithCustomLabel(t *testing.T) {
+func TestDiscoverPodsWithCustomLabel(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
 
 	// Create pods with different labels
@@ -508,9 +404,9 @@ func TestGetMediaDriverPodsWithCustomLabel(t *testing.T) {
 	}
 
 	// Test with custom label selector - should only find the custom pod
-	result, err := getMediaDriverPods(clientset, "test-namespace", "app=aeron-driver", 0)
+	result, err := discoverTestPods(clientset, "app=aeron-driver", 0)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 
 	if len(result) != 1 {
@@ -523,9 +419,9 @@ func TestGetMediaDriverPodsWithCustomLabel(t *testing.T) {
 	}
 
 	// Test with default label selector - should only find the default pod
-	result, err = getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+	result, err = discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 
 	if len(result) != 1 {
@@ -538,103 +434,7 @@ func TestGetMediaDriverPodsWithCustomLabel(t *testing.T) {
 	}
 }
 
-func TestGetBootstrapPath(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected string
-	}{
-		{
-			name:     "default path when env not set",
-			envValue: "",
-			expected: "/etc/aeron/bootstrap.properties",
-		},
-		{
-			name:     "custom path from environment",
-			envValue: "/custom/path/bootstrap.properties",
-			expected: "/custom/path/bootstrap.properties",
-		},
-		{
-			name:     "relative path",
-			envValue: "./config/bootstrap.properties",
-			expected: "./config/bootstrap.properties",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("AERON_MD_BOOTSTRAP_PATH", tt.envValue)
-			} else {
-				os.Unsetenv("AERON_MD_BOOTSTRAP_PATH")
-			}
-
-			result := getBootstrapPath()
-			if result != tt.expected {
-				t.Errorf("getBootstrapPath() = %s, expected %s", result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestGetMaxPods(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected int
-	}{
-		{
-			name:     "default max pods when env not set",
-			envValue: "",
-			expected: 0,
-		},
-		{
-			name:     "valid max pods",
-			envValue: "5",
-			expected: 5,
-		},
-		{
-			name:     "zero max pods (unlimited)",
-			envValue: "0",
-			expected: 0,
-		},
-		{
-			name:     "large max pods",
-			envValue: "100",
-			expected: 100,
-		},
-		{
-			name:     "invalid env value - non-numeric",
-			envValue: "invalid",
-			expected: 0,
-		},
-		{
-			name:     "invalid env value - negative",
-			envValue: "-5",
-			expected: 0,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("AERON_MD_MAX_BOOTSTRAP_PODS", tt.envValue)
-			} else {
-				os.Unsetenv("AERON_MD_MAX_BOOTSTRAP_PODS")
-			}
-
-			result := getMaxPods()
-			if result != tt.expected {
-				t.Errorf("getMaxPods() = %d, expected %d", result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestGetMediaDriverPodsWithMaxLimit(t *testing.T) {
+func TestDiscoverPodsWithMaxLimit(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
 
 	// Create 5 pods with different creation times
@@ -655,18 +455,18 @@ func TestGetMediaDriverPodsWithMaxLimit(t *testing.T) {
 	}
 
 	// Test with no limit (0 = unlimited, should get all 5)
-	result, err := getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+	result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 	if len(result) != 5 {
 		t.Errorf("Expected 5 pods with unlimited (0), got %d", len(result))
 	}
 
 	// Test with limit of 3 (should get 3 oldest)
-	result, err = getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 3)
+	result, err = discoverTestPods(clientset, "aeron.io/media-driver=true", 3)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 	if len(result) != 3 {
 		t.Errorf("Expected 3 pods with limit, got %d", len(result))
@@ -681,111 +481,15 @@ func TestGetMediaDriverPodsWithMaxLimit(t *testing.T) {
 	}
 
 	// Test with limit larger than available pods
-	result, err = getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 10)
+	result, err = discoverTestPods(clientset, "aeron.io/media-driver=true", 10)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 	if len(result) != 5 {
 		t.Errorf("Expected 5 pods (all available) with large limit, got %d", len(result))
 	}
 }
 
-func TestGetNamespace(t *testing.T) {
-	tests := []struct {
-		name         string
-		envValue     string
-		expectError  bool
-		expectedName string
-	}{
-		{
-			name:         "custom namespace from environment",
-			envValue:     "custom-namespace",
-			expectError:  false,
-			expectedName: "custom-namespace",
-		},
-		{
-			name:         "production namespace",
-			envValue:     "production",
-			expectError:  false,
-			expectedName: "production",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("AERON_MD_NAMESPACE", tt.envValue)
-			} else {
-				os.Unsetenv("AERON_MD_NAMESPACE")
-			}
-
-			result, err := getNamespace()
-
-			if tt.expectError && err == nil {
-				t.Errorf("Expected error but got none")
-				return
-			}
-
-			if !tt.expectError && err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
-			}
-
-			if !tt.expectError && result != tt.expectedName {
-				t.Errorf("getNamespace() = %s, expected %s", result, tt.expectedName)
-			}
-		})
-	}
-}
-
-func TestGetHostnameSuffix(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected string
-	}{
-		{
-			name:     "default suffix when env not set",
-			envValue: "",
-			expected: ".aeron",
-		},
-		{
-			name:     "custom suffix from environment",
-			envValue: ".custom",
-			expected: ".custom",
-		},
-		{
-			name:     "suffix without dot",
-			envValue: "mysuffix",
-			expected: "mysuffix",
-		},
-		{
-			name:     "empty suffix",
-			envValue: "",
-			expected: ".aeron",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("AERON_MD_HOSTNAME_SUFFIX", tt.envValue)
-			} else {
-				os.Unsetenv("AERON_MD_HOSTNAME_SUFFIX")
-			}
-
-			result := getHostnameSuffix()
-			if result != tt.expected {
-				t.Errorf("getHostnameSuffix() = %s, expected %s", result, tt.expected)
-			}
-		})
-	}
-}
-
 func TestBuildAeronHostname(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -834,22 +538,198 @@ func TestBuildAeronHostname(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			// Set test env values
-			t.Setenv("HOSTNAME", tt.hostname)
-			if tt.suffix != "" {
-				t.Setenv("AERON_MD_HOSTNAME_SUFFIX", tt.suffix)
-			} else {
-				os.Unsetenv("AERON_MD_HOSTNAME_SUFFIX")
+			result := buildAeronHostname(tt.hostname, tt.namespace, tt.suffix)
+			if result != tt.expected {
+				t.Errorf("buildAeronHostname(%s) = %s, expected %s", tt.namespace, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetPodResolverPort(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		set        bool
+		expected   int
+	}{
+		{
+			name:     "no annotation uses default",
+			expected: 8050,
+		},
+		{
+			name:       "valid annotation",
+			annotation: "9050",
+			set:        true,
+			expected:   9050,
+		},
+		{
+			name:       "non-numeric annotation uses default",
+			annotation: "invalid",
+			set:        true,
+			expected:   8050,
+		},
+		{
+			name:       "out of range annotation uses default",
+			annotation: "70000",
+			set:        true,
+			expected:   8050,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := createTestPod("aeron-1", "10.0.0.1", "Running", time.Now())
+			if tt.set {
+				pod.Annotations = map[string]string{"aeron.io/resolver-port": tt.annotation}
 			}
 
-			result := buildAeronHostname(tt.namespace)
+			result := getPodResolverPort(pod, 8050)
 			if result != tt.expected {
-				t.Errorf("buildAeronHostname(%s) = %s, expected %s", tt.namespace, result, tt.expected)
+				t.Errorf("getPodResolverPort() = %d, expected %d", result, tt.expected)
 			}
 		})
 	}
 }
 
+func TestGetPodResolverName(t *testing.T) {
+	pod := createTestPod("aeron-1", "10.0.0.1", "Running", time.Now())
+	if result := getPodResolverName(pod, "aeron-1.test.aeron"); result != "aeron-1.test.aeron" {
+		t.Errorf("getPodResolverName() = %s, expected default aeron-1.test.aeron", result)
+	}
+
+	pod.Annotations = map[string]string{"aeron.io/resolver-name": "custom.name"}
+	if result := getPodResolverName(pod, "aeron-1.test.aeron"); result != "custom.name" {
+		t.Errorf("getPodResolverName() = %s, expected custom.name", result)
+	}
+}
+
+func TestDiscoverPodsWithResolverOverrides(t *testing.T) {
+	defaultPod := createTestPod("aeron-default", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	overriddenPod := createTestPod("aeron-override", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	overriddenPod.Annotations = map[string]string{
+		"aeron.io/resolver-port": "9050",
+		"aeron.io/resolver-name": "override.aeron",
+	}
+
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range []corev1.Pod{defaultPod, overriddenPod} {
+		_, err := clientset.CoreV1().Pods("test-namespace").Create(context.TODO(), &pod, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create test pod: %v", err)
+		}
+	}
+
+	result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	if len(result) != 2 {
+		t.Fatalf("DiscoverPods() returned %d pods, expected 2", len(result))
+	}
+
+	expected := []struct {
+		endpoint     string
+		resolverName string
+	}{
+		{endpoint: "10.0.0.1:8050", resolverName: "aeron-default.test-namespace.aeron"},
+		{endpoint: "10.0.0.2:9050", resolverName: "override.aeron"},
+	}
+	for i, pod := range result {
+		if pod.Endpoint() != expected[i].endpoint {
+			t.Errorf("Pod %d endpoint = %s, expected %s", i, pod.Endpoint(), expected[i].endpoint)
+		}
+		if pod.ResolverName != expected[i].resolverName {
+			t.Errorf("Pod %d resolver name = %s, expected %s", i, pod.ResolverName, expected[i].resolverName)
+		}
+	}
+}
+
+func TestDiscoverPodsWithDeclaredNetwork(t *testing.T) {
+	// Mid migration, one pod has moved to the new network and declares it
+	status := `[{"name":"test-namespace/old-net","interface":"net1","ips":["192.168.1.%d"]},` +
+		`{"name":"test-namespace/new-net","interface":"net2","ips":["192.168.2.%d"]}]`
+	oldPod := createTestPod("aeron-old", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	oldPod.Annotations = map[string]string{NetworkStatusAnnotation: fmt.Sprintf(status, 1, 1)}
+	newPod := createTestPod("aeron-new", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	newPod.Annotations = map[string]string{
+		NetworkStatusAnnotation: fmt.Sprintf(status, 2, 2),
+		NetworkAnnotation:       "new-net",
+	}
+	ifacePod := createTestPod("aeron-iface", "10.0.0.3", "Running", time.Now().Add(-1*time.Minute))
+	ifacePod.Annotations = map[string]string{
+		NetworkStatusAnnotation:    fmt.Sprintf(status, 3, 3),
+		NetworkInterfaceAnnotation: "net2",
+	}
+
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range []corev1.Pod{oldPod, newPod, ifacePod} {
+		pod.Namespace = "test-namespace"
+		if _, err := clientset.CoreV1().Pods("test-namespace").Create(context.TODO(), &pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create test pod: %v", err)
+		}
+	}
+
+	opts := testOptions(clientset)
+	opts.SecondaryInterface = SecondaryInterface{NetworkName: "old-net"}
+	result, _, err := DiscoverPods(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+
+	expected := []struct {
+		ip     string
+		source string
+	}{
+		{"192.168.1.1", IPSourceNetworkName},
+		{"192.168.2.2", IPSourceNetworkName},
+		// Declaring only an interface is not overridden by the default network name
+		{"192.168.2.3", IPSourceInterfaceName},
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("DiscoverPods() returned %d pods, expected %d", len(result), len(expected))
+	}
+	for i, pod := range result {
+		if pod.IP != expected[i].ip || pod.IPSource != expected[i].source {
+			t.Errorf("Pod %s = (%s, %s), expected (%s, %s)", pod.Name, pod.IP, pod.IPSource, expected[i].ip, expected[i].source)
+		}
+	}
+}
+
+func TestDiscoverPodsPrefersPublishedIdentity(t *testing.T) {
+	// The published identity wins over what network-status would select, and over the pod's overrides
+	pod := createTestPodWithMultus("aeron-1", "10.0.0.1", "mynet", "10.0.0.2", time.Now().Add(-5*time.Minute))
+	pod.Annotations["aeron.io/published-address"] = "10.0.0.9"
+	pod.Annotations["aeron.io/published-name"] = "aeron-1.published.aeron"
+	pod.Annotations["aeron.io/published-port"] = "9050"
+	pod.Annotations["aeron.io/resolver-address"] = "10.0.0.8"
+	pod.Annotations["aeron.io/resolver-name"] = "aeron-1.override.aeron"
+	pod.Annotations["aeron.io/resolver-port"] = "7050"
+
+	clientset := fake.NewSimpleClientset()
+	_, err := clientset.CoreV1().Pods("test-namespace").Create(context.TODO(), &pod, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create test pod: %v", err)
+	}
+
+	result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	if len(result) != 1 {
+		t.Fatalf("DiscoverPods() returned %d pods, expected 1", len(result))
+	}
+	if result[0].Endpoint() != "10.0.0.9:9050" {
+		t.Errorf("Pod endpoint = %s, expected 10.0.0.9:9050", result[0].Endpoint())
+	}
+	if result[0].ResolverName != "aeron-1.published.aeron" {
+		t.Errorf("Pod resolver name = %s, expected aeron-1.published.aeron", result[0].ResolverName)
+	}
+	if result[0].IPSource != IPSourcePublished {
+		t.Errorf("Pod IP source = %s, expected %s", result[0].IPSource, IPSourcePublished)
+	}
+}
+
 func TestMainExitsWithErrorWhenNoPodsFound(t *testing.T) {
 	// This test verifies that when no pods are found, the application exits with code 1
 	// We can\"t easily test os.Exit(1) directly, but we can test the logic that leads to it
@@ -857,10 +737,10 @@ func TestMainExitsWithErrorWhenNoPodsFound(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
 	// Don\"t add any pods - this will simulate no pods found
 
-	// Test that getMediaDriverPods returns empty result
-	result, err := getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+	// Test that DiscoverPods returns empty result
+	result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 
 	// Verify that no pods are returned (which should trigger exit code 1 in main)
@@ -888,10 +768,10 @@ func TestMainExitsWithErrorWhenOnlyPodsWithoutIPFound(t *testing.T) {
 		}
 	}
 
-	// Test that getMediaDriverPods returns empty result (pods without IPs are filtered out)
-	result, err := getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+	// Test that DiscoverPods returns empty result (pods without IPs are filtered out)
+	result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 
 	// Verify that no pods are returned (which should trigger exit code 1 in main)
@@ -920,9 +800,9 @@ func TestMainExitsWithErrorWhenWrongLabelSelector(t *testing.T) {
 	}
 
 	// Test with a label selector that won\"t match any pods
-	result, err := getMediaDriverPods(clientset, "test-namespace", "app=nonexistent", 0)
+	result, err := discoverTestPods(clientset, "app=nonexistent", 0)
 	if err != nil {
-		t.Fatalf("getMediaDriverPods() error = %v", err)
+		t.Fatalf("DiscoverPods() error = %v", err)
 	}
 
 	// Verify that no pods are returned (which should trigger exit code 1 in main)
@@ -1008,66 +888,7 @@ func TestResolverInterfaceUsesShortHostname(t *testing.T) {
 	}
 }
 
-func TestGetCurrentHostname(t *testing.T) {
-	tests := []struct {
-		name     string
-		envValue string
-		expected string
-	}{
-		{
-			name:     "hostname from environment",
-			envValue: "test-pod-123",
-			expected: "test-pod-123",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-
-			// Set test env value
-			if tt.envValue != "" {
-				t.Setenv("HOSTNAME", tt.envValue)
-			} else {
-				os.Unsetenv("HOSTNAME")
-			}
-
-			result := getCurrentHostname()
-			if result != tt.expected {
-				t.Errorf("getCurrentHostname() = %s, expected %s", result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestGetIP(t *testing.T) {
-	tests := []struct {
-		name     string
-		pod      corev1.Pod
-		expected string
-	}{
-		{
-			name:     "pod with primary IP",
-			pod:      createTestPod("pod-with-primary", "10.0.0.1", "Running", time.Now().Add(-5*time.Minute)),
-			expected: "10.0.0.1",
-		},
-		{
-			name: "pod with secondary interface",
-			pod: createTestPodWithSecondaryInterface("pod-with-secondary", "10.0.0.1", "10.0.0.2", "Running", "custom-network", "net1", time.Now().Add(-5*time.Minute)),
-			expected: "10.0.0.2",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, _ := getIP(tt.pod)
-			if result != tt.expected {
-				t.Errorf("getIP() = %s, expected %s", result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestGetCurrentPod(t *testing.T) {
+func TestCurrentPod(t *testing.T) {
 	namespace := "test-namespace"
 	pod := createTestPod("pod-with-primary", "10.0.0.1", "Running", time.Now().Add(-5*time.Minute))
 
@@ -1076,9 +897,13 @@ func TestGetCurrentPod(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create test pod: %v", err)
 	}
-	t.Setenv("HOSTNAME", pod.Name)
+	opts := testOptions(clientset)
+	opts.PodName = pod.Name
 
-	result := getCurrentPod(clientset, namespace)
+	result, err := CurrentPod(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("CurrentPod() error = %v", err)
+	}
 	expected := corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "pod-with-primary",
@@ -1089,233 +914,14 @@ func TestGetCurrentPod(t *testing.T) {
 	}
 
 	if result.Name != expected.Name {
-		t.Errorf("getCurrentPod() Name = %s, expected %s", result.Name, expected.Name)
+		t.Errorf("CurrentPod() Name = %s, expected %s", result.Name, expected.Name)
 	}
 	if result.Status.PodIP != expected.Status.PodIP {
-		t.Errorf("getCurrentPod() PodIP = %s, expected %s", result.Status.PodIP, expected.Status.PodIP)
-	}
-}
-
-func TestParseNetworksAnnotation(t *testing.T) {
-	tests := []struct {
-		name        string
-		annotation  string
-		expected    []string
-		expectError bool
-	}{
-		{
-			name:        "empty annotation",
-			annotation:  "",
-			expected:    nil,
-			expectError: false,
-		},
-		{
-			name:        "simple string",
-			annotation:  "mynet",
-			expected:    []string{"mynet"},
-			expectError: false,
-		},
-		{
-			name:        "comma-separated",
-			annotation:  "mynet1,mynet2",
-			expected:    []string{"mynet1", "mynet2"},
-			expectError: false,
-		},
-		{
-			name:        "comma-separated with spaces",
-			annotation:  "mynet1, mynet2, mynet3",
-			expected:    []string{"mynet1", "mynet2", "mynet3"},
-			expectError: false,
-		},
-		{
-			name:        "JSON array format",
-			annotation:  `[{"name":"mynet1"},{"name":"mynet2"}]`,
-			expected:    []string{"mynet1", "mynet2"},
-			expectError: false,
-		},
-		{
-			name:        "JSON array format with single network",
-			annotation:  `[{"name":"custom-network"}]`,
-			expected:    []string{"custom-network"},
-			expectError: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseNetworksAnnotation(tt.annotation)
-
-			if tt.expectError && err == nil {
-				t.Errorf("Expected error but got none")
-				return
-			}
-
-			if !tt.expectError && err != nil {
-				t.Errorf("Unexpected error: %v", err)
-				return
-			}
-
-			if len(result) != len(tt.expected) {
-				t.Errorf("Expected %d networks, got %d", len(tt.expected), len(result))
-				return
-			}
-
-			for i, name := range result {
-				if name != tt.expected[i] {
-					t.Errorf("Network %d: expected %s, got %s", i, tt.expected[i], name)
-				}
-			}
-		})
-	}
-}
-
-func TestValidateMultusNetworkStatus(t *testing.T) {
-	tests := []struct {
-		name     string
-		pod      corev1.Pod
-		expected bool
-	}{
-		{
-			name: "pod without networks annotation - valid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:        "pod-no-multus",
-					Annotations: map[string]string{},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "pod with networks annotation but no network-status - invalid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-missing-status",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks": "mynet",
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "pod with networks and matching network-status with IP - valid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-valid-multus",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "mynet",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"mynet","interface":"net1","ips":["10.0.0.2"]}]`,
-					},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "pod with networks but network-status missing that network - invalid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-wrong-network",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "mynet",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"othernet","interface":"net1","ips":["10.0.0.2"]}]`,
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "pod with networks and network-status but no IP - invalid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-no-ip",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "mynet",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"mynet","interface":"net1","ips":[]}]`,
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "pod with multiple networks all valid - valid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-multi-valid",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "net1,net2",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"net1","interface":"net1","ips":["10.0.0.1"]},{"name":"net2","interface":"net2","ips":["10.0.0.2"]}]`,
-					},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "pod with multiple networks but one missing - invalid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-multi-invalid",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "net1,net2",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"net1","interface":"net1","ips":["10.0.0.1"]}]`,
-					},
-				},
-			},
-			expected: false,
-		},
-		{
-			name: "pod with JSON array networks format - valid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: "pod-json-format",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        `[{"name":"custom-net"}]`,
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"custom-net","interface":"net1","ips":["10.0.0.3"]}]`,
-					},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "pod with namespace-qualified network name in status - valid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "pod-namespace-qualified",
-					Namespace: "test-ns",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "aeron",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"test-ns/aeron","interface":"net1","ips":["192.168.1.201"]}]`,
-					},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "pod with wrong namespace in qualified network name - invalid",
-			pod: corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "pod-wrong-namespace",
-					Namespace: "test-ns",
-					Annotations: map[string]string{
-						"k8s.v1.cni.cncf.io/networks":        "aeron",
-						"k8s.v1.cni.cncf.io/network-status": `[{"name":"other-ns/aeron","interface":"net1","ips":["192.168.1.201"]}]`,
-					},
-				},
-			},
-			expected: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := validateMultusNetworkStatus(tt.pod)
-			if result != tt.expected {
-				t.Errorf("validateMultusNetworkStatus() = %v, expected %v", result, tt.expected)
-			}
-		})
+		t.Errorf("CurrentPod() PodIP = %s, expected %s", result.Status.PodIP, expected.Status.PodIP)
 	}
 }
 
-func TestGetMediaDriverPodsWithMultusValidation(t *testing.T) {
+func TestDiscoverPodsWithMultusValidation(t *testing.T) {
 	tests := []struct {
 		name     string
 		pods     []corev1.Pod
@@ -1364,9 +970,9 @@ func TestGetMediaDriverPodsWithMultusValidation(t *testing.T) {
 				}
 			}
 
-			result, err := getMediaDriverPods(clientset, "test-namespace", "aeron.io/media-driver=true", 0)
+			result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
 			if err != nil {
-				t.Fatalf("getMediaDriverPods() error = %v", err)
+				t.Fatalf("DiscoverPods() error = %v", err)
 			}
 
 			if len(result) != tt.expected {
@@ -1377,6 +983,23 @@ func TestGetMediaDriverPodsWithMultusValidation(t *testing.T) {
 }
 
 // Helper functions for creating test pods
+// testOptions returns the default options for test-namespace, using the given clientset
+func testOptions(clientset kubernetes.Interface) Options {
+	opts := DefaultOptions()
+	opts.Clientset = clientset
+	opts.Namespace = "test-namespace"
+	return opts
+}
+
+// discoverTestPods runs DiscoverPods in test-namespace with the given label selector and limit
+func discoverTestPods(clientset kubernetes.Interface, labelSelector string, maxPods int) ([]PodInfo, error) {
+	opts := testOptions(clientset)
+	opts.LabelSelector = labelSelector
+	opts.MaxPods = maxPods
+	pods, _, err := DiscoverPods(context.TODO(), opts)
+	return pods, err
+}
+
 func createTestPod(name, ip, phase string, creationTime time.Time) corev1.Pod {
 	return createTestPodWithLabel(name, ip, phase, creationTime, "aeron.io/media-driver", "true")
 }
@@ -0,0 +1,171 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CnCFileName is the name of the media driver's command and control file within its aeron dir
+const CnCFileName = "cnc.dat"
+
+// Layout of the CnC file, see CncFileDescriptor in the Aeron sources
+// The header is followed by the to-driver, to-clients, counters metadata, counters values and error log buffers
+const (
+	cncVersionOffset                = 0
+	cncToDriverLengthOffset         = 4
+	cncToClientsLengthOffset        = 8
+	cncCountersMetadataLengthOffset = 12
+	cncCountersValuesLengthOffset   = 16
+	cncHeaderLength                 = 128
+
+	// Only major version 0 of the CnC layout is understood
+	cncMajorVersion = 0
+
+	// Counters metadata records, see CountersReader in the Aeron sources
+	counterMetadataLength      = 512
+	counterStateOffset         = 0
+	counterTypeIDOffset        = 4
+	counterLabelLengthOffset   = 128
+	counterLabelOffset         = 132
+	counterMaxLabelLength      = counterMetadataLength - counterLabelOffset
+	counterValueLength         = 128
+	counterRecordAllocated     = 1
+	counterRecordUnused        = 0
+	resolverNeighborsTypeID    = 15
+	resolverCacheEntriesTypeID = 16
+	resolverNeighborsLabel     = "Resolver neighbors"
+	resolverBoundLabelPrefix   = ": bound "
+)
+
+// States of the driver name resolver, as reported by ResolverStatus
+const (
+	ResolverStateAbsent  = "Absent"
+	ResolverStateUnbound = "Unbound"
+	ResolverStateBound   = "Bound"
+)
+
+// ErrCnCNotReady means the CnC file is missing, or the media driver has not finished initialising it
+var ErrCnCNotReady = errors.New("media driver CnC file not ready")
+
+// Counter is one allocated counter from the media driver's counters buffers
+type Counter struct {
+	ID     int
+	TypeID int32
+	Label  string
+	Value  int64
+}
+
+// ResolverStatus summarises the driver name resolver counters
+type ResolverStatus struct {
+	// State is one of the ResolverState constants
+	State string
+	// BoundAddress is the address the resolver is bound to, once it has bound
+	BoundAddress string
+	Neighbors    int64
+	CacheEntries int64
+}
+
+// ReadCounters reads every allocated counter from the CnC file in aeronDir
+func ReadCounters(aeronDir string) ([]Counter, error) {
+	var counters []Counter
+	err := withCnCFile(filepath.Join(aeronDir, CnCFileName), func(cnc []byte) error {
+		var err error
+		counters, err = ParseCounters(cnc)
+		return err
+	})
+	return counters, err
+}
+
+// ParseCounters decodes the allocated counters from the contents of a CnC file
+func ParseCounters(cnc []byte) ([]Counter, error) {
+	if len(cnc) < cncHeaderLength {
+		return nil, fmt.Errorf("%w: file is %d bytes, shorter than its header", ErrCnCNotReady, len(cnc))
+	}
+
+	// The driver writes the version last, once the file is fully initialised
+	version := binary.LittleEndian.Uint32(cnc[cncVersionOffset:])
+	if version == 0 {
+		return nil, fmt.Errorf("%w: version not set", ErrCnCNotReady)
+	}
+	if major := version >> 16; major != cncMajorVersion {
+		return nil, fmt.Errorf("unsupported CnC version %d.%d.%d", major, (version>>8)&0xff, version&0xff)
+	}
+
+	toDriverLength := int64(binary.LittleEndian.Uint32(cnc[cncToDriverLengthOffset:]))
+	toClientsLength := int64(binary.LittleEndian.Uint32(cnc[cncToClientsLengthOffset:]))
+	metadataLength := int64(binary.LittleEndian.Uint32(cnc[cncCountersMetadataLengthOffset:]))
+	valuesLength := int64(binary.LittleEndian.Uint32(cnc[cncCountersValuesLengthOffset:]))
+
+	metadataOffset := cncHeaderLength + toDriverLength + toClientsLength
+	valuesOffset := metadataOffset + metadataLength
+	if valuesOffset+valuesLength > int64(len(cnc)) {
+		return nil, fmt.Errorf("CnC file is %d bytes, too short for its counters buffers ending at %d", len(cnc), valuesOffset+valuesLength)
+	}
+	metadata := cnc[metadataOffset:valuesOffset]
+	values := cnc[valuesOffset : valuesOffset+valuesLength]
+
+	var counters []Counter
+	for id := 0; (id+1)*counterMetadataLength <= len(metadata); id++ {
+		record := metadata[id*counterMetadataLength : (id+1)*counterMetadataLength]
+		state := int32(binary.LittleEndian.Uint32(record[counterStateOffset:]))
+		if state == counterRecordUnused {
+			// Counters are allocated in order, so the first unused record ends the list
+			break
+		}
+		if state != counterRecordAllocated {
+			continue
+		}
+		if (id+1)*counterValueLength > len(values) {
+			return nil, fmt.Errorf("counter %d has no value in the %d byte values buffer", id, len(values))
+		}
+
+		labelLength := int(int32(binary.LittleEndian.Uint32(record[counterLabelLengthOffset:])))
+		labelLength = max(0, min(labelLength, counterMaxLabelLength))
+		counters = append(counters, Counter{
+			ID:     id,
+			TypeID: int32(binary.LittleEndian.Uint32(record[counterTypeIDOffset:])),
+			Label:  string(record[counterLabelOffset : counterLabelOffset+labelLength]),
+			Value:  int64(binary.LittleEndian.Uint64(values[id*counterValueLength:])),
+		})
+	}
+	return counters, nil
+}
+
+// GetResolverStatus finds the driver name resolver counters
+func GetResolverStatus(counters []Counter) ResolverStatus {
+	status := ResolverStatus{State: ResolverStateAbsent}
+	for _, counter := range counters {
+		switch {
+		case counter.TypeID == resolverNeighborsTypeID || strings.HasPrefix(counter.Label, resolverNeighborsLabel):
+			status.Neighbors = counter.Value
+			status.State = ResolverStateUnbound
+			// The resolver appends its bound address to the label once its socket is bound
+			if _, address, ok := strings.Cut(counter.Label, resolverBoundLabelPrefix); ok {
+				status.State = ResolverStateBound
+				status.BoundAddress = strings.TrimSpace(address)
+			}
+		case counter.TypeID == resolverCacheEntriesTypeID:
+			status.CacheEntries = counter.Value
+		}
+	}
+	return status
+}
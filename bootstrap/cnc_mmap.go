@@ -0,0 +1,51 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build linux || darwin
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// withCnCFile memory-maps the CnC file read-only for the duration of fn, as AeronStat does,
+// so the driver's counters are read in place rather than copied
+func withCnCFile(path string, fn func(cnc []byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCnCNotReady, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("%w: %s is empty", ErrCnCNotReady, path)
+	}
+
+	cnc, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("failed to map %s: %v", path, err)
+	}
+	defer syscall.Munmap(cnc)
+
+	return fn(cnc)
+}
@@ -0,0 +1,33 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+//go:build !(linux || darwin)
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+)
+
+// withCnCFile reads the CnC file for fn, on platforms without the memory-mapped reader
+func withCnCFile(path string, fn func(cnc []byte) error) error {
+	cnc, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCnCNotReady, err)
+	}
+	return fn(cnc)
+}
@@ -0,0 +1,169 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testCounter is a counter to write into a synthetic CnC file
+type testCounter struct {
+	typeID int32
+	label  string
+	value  int64
+	state  int32
+}
+
+// buildTestCnC builds a synthetic CnC file with the same layout as a media driver's
+func buildTestCnC(version uint32, counters []testCounter) []byte {
+	const toDriverLength, toClientsLength, errorLogLength = 256, 256, 64
+	metadataLength := (len(counters) + 2) * counterMetadataLength
+	valuesLength := (len(counters) + 2) * counterValueLength
+
+	cnc := make([]byte, cncHeaderLength+toDriverLength+toClientsLength+metadataLength+valuesLength+errorLogLength)
+	binary.LittleEndian.PutUint32(cnc[cncVersionOffset:], version)
+	binary.LittleEndian.PutUint32(cnc[cncToDriverLengthOffset:], toDriverLength)
+	binary.LittleEndian.PutUint32(cnc[cncToClientsLengthOffset:], toClientsLength)
+	binary.LittleEndian.PutUint32(cnc[cncCountersMetadataLengthOffset:], uint32(metadataLength))
+	binary.LittleEndian.PutUint32(cnc[cncCountersValuesLengthOffset:], uint32(valuesLength))
+
+	metadata := cnc[cncHeaderLength+toDriverLength+toClientsLength:]
+	values := metadata[metadataLength:]
+	for id, counter := range counters {
+		record := metadata[id*counterMetadataLength:]
+		state := counter.state
+		if state == 0 {
+			state = counterRecordAllocated
+		}
+		binary.LittleEndian.PutUint32(record[counterStateOffset:], uint32(state))
+		binary.LittleEndian.PutUint32(record[counterTypeIDOffset:], uint32(counter.typeID))
+		binary.LittleEndian.PutUint32(record[counterLabelLengthOffset:], uint32(len(counter.label)))
+		copy(record[counterLabelOffset:], counter.label)
+		binary.LittleEndian.PutUint64(values[id*counterValueLength:], uint64(counter.value))
+	}
+	return cnc
+}
+
+// cncVersion020 is the CnC version written by current media drivers
+const cncVersion020 = 0<<16 | 2<<8 | 0
+
+func TestParseCounters(t *testing.T) {
+	cnc := buildTestCnC(cncVersion020, []testCounter{
+		{typeID: 0, label: "Bytes sent", value: 1024},
+		{typeID: 0, label: "Reclaimed", value: 7, state: -1},
+		{typeID: resolverNeighborsTypeID, label: "Resolver neighbors: bound 10.0.0.1:8050", value: 2},
+	})
+
+	counters, err := ParseCounters(cnc)
+	if err != nil {
+		t.Fatalf("ParseCounters() error = %v", err)
+	}
+	if len(counters) != 2 {
+		t.Fatalf("ParseCounters() returned %d counters, expected 2: %+v", len(counters), counters)
+	}
+	if counters[0].Label != "Bytes sent" || counters[0].Value != 1024 || counters[0].ID != 0 {
+		t.Errorf("Unexpected first counter %+v", counters[0])
+	}
+	if counters[1].ID != 2 || counters[1].TypeID != resolverNeighborsTypeID || counters[1].Value != 2 {
+		t.Errorf("Unexpected resolver counter %+v", counters[1])
+	}
+}
+
+func TestParseCountersErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		cnc       []byte
+		notReady  bool
+		expectErr bool
+	}{
+		{name: "empty file", cnc: nil, notReady: true},
+		{name: "version not yet written", cnc: buildTestCnC(0, nil), notReady: true},
+		{name: "unsupported major version", cnc: buildTestCnC(1<<16, nil)},
+		{name: "truncated counters buffers", cnc: buildTestCnC(cncVersion020, []testCounter{{label: "x"}})[:cncHeaderLength+600]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCounters(tt.cnc)
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if errors.Is(err, ErrCnCNotReady) != tt.notReady {
+				t.Errorf("ParseCounters() error = %v, expected not ready = %v", err, tt.notReady)
+			}
+		})
+	}
+}
+
+func TestGetResolverStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		counters []Counter
+		expected ResolverStatus
+	}{
+		{
+			name:     "no resolver configured",
+			counters: []Counter{{TypeID: 0, Label: "Bytes sent", Value: 1}},
+			expected: ResolverStatus{State: ResolverStateAbsent},
+		},
+		{
+			name: "resolver bound",
+			counters: []Counter{
+				{TypeID: resolverNeighborsTypeID, Label: "Resolver neighbors: bound 10.0.0.1:8050", Value: 2},
+				{TypeID: resolverCacheEntriesTypeID, Label: "Resolver cache entries", Value: 3},
+			},
+			expected: ResolverStatus{State: ResolverStateBound, BoundAddress: "10.0.0.1:8050", Neighbors: 2, CacheEntries: 3},
+		},
+		{
+			name:     "resolver matched by label, not yet bound",
+			counters: []Counter{{TypeID: 99, Label: "Resolver neighbors", Value: 0}},
+			expected: ResolverStatus{State: ResolverStateUnbound},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := GetResolverStatus(tt.counters); result != tt.expected {
+				t.Errorf("GetResolverStatus() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadCounters(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadCounters(dir); !errors.Is(err, ErrCnCNotReady) {
+		t.Errorf("ReadCounters() of a missing file error = %v, expected %v", err, ErrCnCNotReady)
+	}
+
+	cnc := buildTestCnC(cncVersion020, []testCounter{{typeID: resolverNeighborsTypeID, label: "Resolver neighbors: bound 10.0.0.1:8050", value: 2}})
+	if err := os.WriteFile(filepath.Join(dir, CnCFileName), cnc, 0644); err != nil {
+		t.Fatalf("Failed to write CnC file: %v", err)
+	}
+
+	counters, err := ReadCounters(dir)
+	if err != nil {
+		t.Fatalf("ReadCounters() error = %v", err)
+	}
+	if status := GetResolverStatus(counters); status.Neighbors != 2 || status.State != ResolverStateBound {
+		t.Errorf("Unexpected resolver status %+v", status)
+	}
+}
@@ -0,0 +1,197 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Where the controller publishes each pod's neighbor assignment, and where pods wait for it
+const (
+	AssignmentConfigMap  = "configmap"
+	AssignmentAnnotation = "annotation"
+)
+
+const (
+	// AssignmentAnnotationKey holds a pod's assigned neighbors as a JSON array of FabricMember
+	AssignmentAnnotationKey = "aeron.io/bootstrap-assignment"
+
+	// controllerFieldManager is the server-side apply field manager of the controller
+	controllerFieldManager = fieldManager + "-controller"
+)
+
+// ControllerOptions configures the controller that assigns neighbors for the whole fabric
+type ControllerOptions struct {
+	// Options selects the media driver pods and configures their rendering, MaxPods limiting each pod's neighbors
+	Options
+	// Strategy chooses each pod's neighbors, one of the Strategy constants
+	Strategy string
+	// Publish selects where assignments are published, one of the Assignment constants
+	Publish string
+	// FabricConfigMap, if set, names a shared ConfigMap describing every member of the fabric
+	FabricConfigMap string
+	// FabricOwners own the fabric ConfigMap, so it is garbage collected with them
+	FabricOwners []metav1.OwnerReference
+	// ResyncPeriod re-reconciles periodically even without pod changes, 0 disables it
+	ResyncPeriod time.Duration
+}
+
+// Reconcile assigns neighbors to every eligible pod in pods with the configured strategy and publishes
+// the assignments, returning the fabric members and the pods skipped
+func Reconcile(ctx context.Context, copts ControllerOptions, pods []v1.Pod) ([]PodInfo, []PodSkip, error) {
+	var members []PodInfo
+	var skipped []PodSkip
+	podsByKey := make(map[string]v1.Pod, len(pods))
+	nodes := nodeCache{}
+	for _, pod := range pods {
+		podInfo, skip, err := evaluatePod(ctx, pod, copts.Options, nodes)
+		switch {
+		case err != nil:
+			skipped = append(skipped, PodSkip{Name: pod.Name, Namespace: pod.Namespace, Reason: ExplainReasonIPSelectionFailed, Detail: err.Error()})
+		case skip != nil:
+			skipped = append(skipped, *skip)
+		case podInfo.IP == "":
+			skipped = append(skipped, PodSkip{Name: pod.Name, Namespace: pod.Namespace, Reason: ExplainReasonNoIP, Detail: "has no IP address yet"})
+		default:
+			members = append(members, podInfo)
+			podsByKey[podInfo.Key()] = pod
+		}
+	}
+
+	assignments, err := AssignNeighbors(members, copts.Strategy, copts.MaxPods)
+	if err != nil {
+		return members, skipped, err
+	}
+
+	var errs []error
+	for _, member := range members {
+		if err := publishAssignment(ctx, copts, podsByKey[member.Key()], member, assignments[member.Key()]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if copts.FabricConfigMap != "" {
+		if err := ApplyFabricConfigMap(ctx, copts.Options, copts.FabricConfigMap, copts.FabricOwners, members); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	slog.Info("Reconciled media driver fabric", LogKeyNamespace, copts.Namespace, "members", len(members), "skipped", len(skipped))
+	return members, skipped, errors.Join(errs...)
+}
+
+// publishAssignment publishes the neighbors assigned to a pod, unless they are already published
+func publishAssignment(ctx context.Context, copts ControllerOptions, pod v1.Pod, member PodInfo, neighbors []PodInfo) error {
+	membersJSON, err := encodeMembers(neighbors)
+	if err != nil {
+		return err
+	}
+
+	switch copts.Publish {
+	case AssignmentAnnotation:
+		if pod.Annotations[AssignmentAnnotationKey] == membersJSON {
+			return nil
+		}
+		if err := patchPodAnnotations(ctx, copts.Options, pod, map[string]string{AssignmentAnnotationKey: membersJSON}); err != nil {
+			return err
+		}
+	case AssignmentConfigMap, "":
+		var endpoints []string
+		for _, neighbor := range neighbors {
+			endpoints = append(endpoints, neighbor.Endpoint())
+		}
+		data := map[string]string{
+			PropertiesKey:    RenderProperties(endpoints, member.Port, member.ResolverName, member.IP),
+			FabricMembersKey: membersJSON,
+		}
+		written, err := applyOwnedConfigMap(ctx, copts.Options, pod, AssignmentConfigMapName(pod.Name), controllerFieldManager, data)
+		if err != nil || !written {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown assignment publishing %q, expected %s or %s", copts.Publish, AssignmentConfigMap, AssignmentAnnotation)
+	}
+
+	slog.Info("Published neighbor assignment", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, "neighbors", len(neighbors))
+	return nil
+}
+
+// RunController watches the media driver pods and reconciles the fabric whenever they change, until ctx is done
+// Only one controller should run at a time, e.g. under leader election
+func RunController(ctx context.Context, copts ControllerOptions) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(copts.Clientset, copts.ResyncPeriod,
+		informers.WithNamespace(copts.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = copts.LabelSelector
+		}))
+	podInformer := factory.Core().V1().Pods()
+
+	// Coalesce bursts of pod changes into a single reconcile
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	_, err := podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { notify() },
+		UpdateFunc: func(any, any) { notify() },
+		DeleteFunc: func(any) { notify() },
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch media driver pods: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), podInformer.Informer().HasSynced) {
+		return fmt.Errorf("%w: pod cache never synced: %v", ErrAPIUnavailable, ctx.Err())
+	}
+	slog.Info("Watching media driver pods", LogKeyNamespace, copts.Namespace, "selector", copts.LabelSelector, "strategy", copts.Strategy)
+
+	lister := podInformer.Lister().Pods(copts.Namespace)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+			cached, err := lister.List(labels.Everything())
+			if err != nil {
+				slog.Warn("Failed to list cached media driver pods", "error", err)
+				continue
+			}
+			pods := make([]v1.Pod, 0, len(cached))
+			for _, pod := range cached {
+				pods = append(pods, *pod)
+			}
+			// A failed publish is retried on the next pod change or resync
+			if _, _, err := Reconcile(ctx, copts, pods); err != nil {
+				slog.Warn("Failed to reconcile media driver fabric", LogKeyNamespace, copts.Namespace, "error", err)
+			}
+		}
+	}
+}
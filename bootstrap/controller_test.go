@@ -0,0 +1,204 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// controllerTestPods returns three media driver pods in test-namespace, oldest first
+func controllerTestPods() []corev1.Pod {
+	now := time.Now()
+	pods := []corev1.Pod{
+		createTestPod("aeron-0", "10.0.0.1", "Running", now.Add(-30*time.Minute)),
+		createTestPod("aeron-1", "10.0.0.2", "Running", now.Add(-20*time.Minute)),
+		createTestPod("aeron-2", "10.0.0.3", "Running", now.Add(-10*time.Minute)),
+	}
+	for i := range pods {
+		pods[i].Namespace = "test-namespace"
+	}
+	return pods
+}
+
+func newControllerTestClientset(pods []corev1.Pod) *fake.Clientset {
+	var objects []runtime.Object
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	return fake.NewSimpleClientset(objects...)
+}
+
+func TestReconcilePublishesAnnotations(t *testing.T) {
+	pods := controllerTestPods()
+	clientset := newControllerTestClientset(pods)
+	copts := ControllerOptions{Options: testOptions(clientset), Strategy: StrategyRing, Publish: AssignmentAnnotation}
+	copts.MaxPods = 1
+
+	members, skipped, err := Reconcile(context.TODO(), copts, pods)
+	if err != nil || len(members) != 3 || len(skipped) != 0 {
+		t.Fatalf("Reconcile() = (%d members, %d skipped, %v), expected (3, 0, nil)", len(members), len(skipped), err)
+	}
+
+	pod, err := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	assigned, err := decodeMembers(pod.Annotations[AssignmentAnnotationKey])
+	if err != nil {
+		t.Fatalf("Invalid assignment annotation %q: %v", pod.Annotations[AssignmentAnnotationKey], err)
+	}
+	if len(assigned) != 1 || assigned[0].Name != "aeron-0" || assigned[0].Endpoint() != "10.0.0.1:8050" {
+		t.Errorf("aeron-2 assigned %+v, expected its ring successor aeron-0", assigned)
+	}
+
+	// Reconciling unchanged pods publishes nothing new
+	updated, err := clientset.CoreV1().Pods("test-namespace").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list pods: %v", err)
+	}
+	clientset.ClearActions()
+	if _, _, err := Reconcile(context.TODO(), copts, updated.Items); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if actions := clientset.Actions(); len(actions) != 0 {
+		t.Errorf("Expected no API calls for an unchanged fabric, got %v", actions)
+	}
+}
+
+func TestReconcilePublishesConfigMaps(t *testing.T) {
+	pods := controllerTestPods()
+	clientset := newControllerTestClientset(pods)
+	applyAsUpsert(clientset, "configmaps", func() runtime.Object { return &corev1.ConfigMap{} })
+	copts := ControllerOptions{Options: testOptions(clientset), Strategy: StrategyOldest, Publish: AssignmentConfigMap, FabricConfigMap: "aeron-fabric"}
+
+	if _, _, err := Reconcile(context.TODO(), copts, pods); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps("test-namespace").Get(context.TODO(), "aeron-1-aeron-assignment", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected an assignment ConfigMap for aeron-1: %v", err)
+	}
+	expected := RenderProperties([]string{"10.0.0.1:8050", "10.0.0.2:8050", "10.0.0.3:8050"}, 8050, "aeron-1.test-namespace.aeron", "10.0.0.2")
+	if configMap.Data[PropertiesKey] != expected {
+		t.Errorf("aeron-1 properties = %q, expected %q", configMap.Data[PropertiesKey], expected)
+	}
+	if len(configMap.OwnerReferences) != 1 || configMap.OwnerReferences[0].Name != "aeron-1" {
+		t.Errorf("Expected the assignment ConfigMap to be owned by aeron-1, got %+v", configMap.OwnerReferences)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test-namespace").Get(context.TODO(), "aeron-fabric", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected the fabric ConfigMap to be applied: %v", err)
+	}
+}
+
+func TestRunController(t *testing.T) {
+	pods := controllerTestPods()
+	clientset := newControllerTestClientset(pods)
+	copts := ControllerOptions{Options: testOptions(clientset), Strategy: StrategyOldest, Publish: AssignmentAnnotation}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- RunController(ctx, copts) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pod, err := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-0", metav1.GetOptions{})
+		if err == nil && pod.Annotations[AssignmentAnnotationKey] != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Controller never published an assignment for aeron-0")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("RunController() error = %v", err)
+	}
+}
+
+func TestRunWaitsForAssignment(t *testing.T) {
+	pods := controllerTestPods()
+	clientset := newControllerTestClientset(pods)
+	assignmentPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { assignmentPollInterval = 2 * time.Second })
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-1"
+	opts.Assignment = AssignmentAnnotation
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+
+	// Nothing is assigned before the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Run(ctx, opts); !errors.Is(err, ErrNoAssignment) {
+		t.Fatalf("Run() error = %v, expected %v", err, ErrNoAssignment)
+	}
+
+	copts := ControllerOptions{Options: testOptions(clientset), Strategy: StrategyRing, Publish: AssignmentAnnotation}
+	if _, _, err := Reconcile(context.TODO(), copts, pods); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+	content, err := os.ReadFile(opts.BootstrapPath)
+	if err != nil {
+		t.Fatalf("Failed to read bootstrap file: %v", err)
+	}
+	expected := RenderProperties([]string{"10.0.0.3:8050", "10.0.0.1:8050"}, 8050, "aeron-1.test-namespace.aeron", "10.0.0.2")
+	if string(content) != expected {
+		t.Errorf("Bootstrap file = %q, expected %q", content, expected)
+	}
+}
+
+func TestRenderUsesAssignment(t *testing.T) {
+	pods := controllerTestPods()
+	clientset := newControllerTestClientset(pods)
+
+	copts := ControllerOptions{Options: testOptions(clientset), Strategy: StrategyRing, Publish: AssignmentAnnotation}
+	if _, _, err := Reconcile(context.TODO(), copts, pods); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-1"
+	opts.Assignment = AssignmentAnnotation
+	result, err := Render(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	expected := RenderProperties([]string{"10.0.0.3:8050", "10.0.0.1:8050"}, 8050, "aeron-1.test-namespace.aeron", "10.0.0.2")
+	if result.Properties != expected {
+		t.Errorf("Render() properties = %q, expected the assigned %q", result.Properties, expected)
+	}
+}
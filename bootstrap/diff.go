@@ -0,0 +1,109 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"bufio"
+	"slices"
+	"strings"
+)
+
+// NeighborKey is the bootstrap properties key listing the resolver's bootstrap neighbors
+const NeighborKey = "aeron.driver.resolver.bootstrap.neighbor"
+
+// KeyChange describes a property whose value differs between two bootstrap files
+// An empty Old or New means the key was added or removed
+type KeyChange struct {
+	Key string
+	Old string
+	New string
+}
+
+// PropertiesDiff describes how a newly rendered bootstrap file differs from an existing one
+type PropertiesDiff struct {
+	AddedNeighbors   []string
+	RemovedNeighbors []string
+	ChangedKeys      []KeyChange
+}
+
+// Empty reports whether the two bootstrap files are equivalent
+func (d PropertiesDiff) Empty() bool {
+	return len(d.AddedNeighbors) == 0 && len(d.RemovedNeighbors) == 0 && len(d.ChangedKeys) == 0
+}
+
+// ParseProperties parses key=value lines of a Java properties file, ignoring blank lines and comments
+func ParseProperties(content string) map[string]string {
+	properties := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		properties[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return properties
+}
+
+// splitNeighbors splits a comma separated neighbor list, dropping empty entries
+func splitNeighbors(value string) []string {
+	var neighbors []string
+	for _, neighbor := range strings.Split(value, ",") {
+		if neighbor = strings.TrimSpace(neighbor); neighbor != "" {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors
+}
+
+// DiffProperties compares an existing bootstrap file with a newly rendered one, treating the
+// neighbor list as a set so reordering alone is not reported as a change
+func DiffProperties(existing, rendered string) PropertiesDiff {
+	oldProperties := ParseProperties(existing)
+	newProperties := ParseProperties(rendered)
+
+	var diff PropertiesDiff
+	oldNeighbors := splitNeighbors(oldProperties[NeighborKey])
+	newNeighbors := splitNeighbors(newProperties[NeighborKey])
+	for _, neighbor := range newNeighbors {
+		if !slices.Contains(oldNeighbors, neighbor) {
+			diff.AddedNeighbors = append(diff.AddedNeighbors, neighbor)
+		}
+	}
+	for _, neighbor := range oldNeighbors {
+		if !slices.Contains(newNeighbors, neighbor) {
+			diff.RemovedNeighbors = append(diff.RemovedNeighbors, neighbor)
+		}
+	}
+
+	keys := make(map[string]bool)
+	for key := range oldProperties {
+		keys[key] = true
+	}
+	for key := range newProperties {
+		keys[key] = true
+	}
+	for key := range keys {
+		if key == NeighborKey || oldProperties[key] == newProperties[key] {
+			continue
+		}
+		diff.ChangedKeys = append(diff.ChangedKeys, KeyChange{Key: key, Old: oldProperties[key], New: newProperties[key]})
+	}
+	slices.SortFunc(diff.ChangedKeys, func(a, b KeyChange) int { return strings.Compare(a.Key, b.Key) })
+	return diff
+}
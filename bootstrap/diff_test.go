@@ -0,0 +1,131 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseProperties(t *testing.T) {
+	content := "# comment\n\n! also a comment\naeron.name.resolver.supplier=driver\n aeron.driver.resolver.name = aeron-0.aeron \n"
+	expected := map[string]string{
+		"aeron.name.resolver.supplier": "driver",
+		"aeron.driver.resolver.name":   "aeron-0.aeron",
+	}
+	if got := ParseProperties(content); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseProperties() = %v, expected %v", got, expected)
+	}
+}
+
+func TestDiffProperties(t *testing.T) {
+	existing := RenderProperties([]string{"10.0.0.1:8050", "10.0.0.2:8050"}, 8050, "aeron-0.aeron", "10.0.0.1")
+
+	tests := []struct {
+		name     string
+		existing string
+		rendered string
+		expected PropertiesDiff
+	}{
+		{
+			name:     "identical",
+			existing: existing,
+			rendered: existing,
+		},
+		{
+			name:     "reordered neighbors",
+			existing: existing,
+			rendered: RenderProperties([]string{"10.0.0.2:8050", "10.0.0.1:8050"}, 8050, "aeron-0.aeron", "10.0.0.1"),
+		},
+		{
+			name:     "neighbor replaced",
+			existing: existing,
+			rendered: RenderProperties([]string{"10.0.0.1:8050", "10.0.0.3:8050"}, 8050, "aeron-0.aeron", "10.0.0.1"),
+			expected: PropertiesDiff{
+				AddedNeighbors:   []string{"10.0.0.3:8050"},
+				RemovedNeighbors: []string{"10.0.0.2:8050"},
+			},
+		},
+		{
+			name:     "resolver interface changed",
+			existing: existing,
+			rendered: RenderProperties([]string{"10.0.0.1:8050", "10.0.0.2:8050"}, 8050, "aeron-0.aeron", "10.0.0.9"),
+			expected: PropertiesDiff{
+				ChangedKeys: []KeyChange{{Key: "aeron.driver.resolver.interface", Old: "10.0.0.1:8050", New: "10.0.0.9:8050"}},
+			},
+		},
+		{
+			name:     "no existing file",
+			existing: "",
+			rendered: RenderProperties([]string{"10.0.0.1:8050"}, 8050, "aeron-0.aeron", "10.0.0.1"),
+			expected: PropertiesDiff{
+				AddedNeighbors: []string{"10.0.0.1:8050"},
+				ChangedKeys: []KeyChange{
+					{Key: "aeron.driver.resolver.interface", New: "10.0.0.1:8050"},
+					{Key: "aeron.driver.resolver.name", New: "aeron-0.aeron"},
+					{Key: "aeron.name.resolver.supplier", New: "driver"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffProperties(tt.existing, tt.rendered)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("DiffProperties() = %+v, expected %+v", got, tt.expected)
+			}
+			if got.Empty() != tt.expected.Empty() {
+				t.Errorf("Empty() = %v, expected %v", got.Empty(), tt.expected.Empty())
+			}
+		})
+	}
+}
+
+func TestRenderDoesNotWrite(t *testing.T) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	self.Namespace = "test-namespace"
+	clientset := fake.NewSimpleClientset(&self)
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+
+	result, err := Render(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if result.Written {
+		t.Errorf("Expected Render() not to report a write")
+	}
+	expected := RenderProperties([]string{"10.0.0.1:8050"}, 8050, "aeron-0.test-namespace.aeron", "10.0.0.1")
+	if result.Properties != expected {
+		t.Errorf("Render() properties = %q, expected %q", result.Properties, expected)
+	}
+	if _, err := os.Stat(opts.BootstrapPath); !os.IsNotExist(err) {
+		t.Errorf("Expected Render() not to create %s, stat error = %v", opts.BootstrapPath, err)
+	}
+	if len(clientset.Actions()) != 2 {
+		t.Errorf("Expected only the get and list actions, got %v", clientset.Actions())
+	}
+}
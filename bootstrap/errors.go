@@ -0,0 +1,37 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"errors"
+)
+
+// Failure modes callers can tell apart with errors.Is
+var (
+	// ErrNoPeers means no suitable media driver pods were found, so no bootstrap file was written
+	ErrNoPeers = errors.New("no suitable media driver pods found")
+	// ErrSelfNotFound means the bootstrapping pod could not find its own pod object
+	ErrSelfNotFound = errors.New("current pod not found")
+	// ErrMultusNotReady means the bootstrapping pod requests Multus networks that have no usable network-status yet
+	ErrMultusNotReady = errors.New("multus network status not ready")
+	// ErrAPIUnavailable means the Kubernetes API could not be reached, even after retries
+	ErrAPIUnavailable = errors.New("kubernetes API unavailable")
+	// ErrOutputConflict means the output ConfigMap or Secret has fields owned by another field manager
+	ErrOutputConflict = errors.New("output fields managed by another field manager")
+	// ErrNoAssignment means the controller did not assign neighbors to the bootstrapping pod before the deadline
+	ErrNoAssignment = errors.New("no neighbor assignment from the controller")
+)
@@ -0,0 +1,78 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCurrentPodNotFound(t *testing.T) {
+	opts := testOptions(fake.NewSimpleClientset())
+	opts.PodName = "aeron-0"
+
+	_, err := CurrentPod(context.TODO(), opts)
+	if !errors.Is(err, ErrSelfNotFound) {
+		t.Errorf("CurrentPod() error = %v, expected %v", err, ErrSelfNotFound)
+	}
+}
+
+func TestCallWithRetryExhaustedIsAPIUnavailable(t *testing.T) {
+	useFastBackoff(t, 2)
+
+	err := callWithRetry(context.TODO(), Options{}, "list_pods", func(ctx context.Context) error {
+		return apierrors.NewServiceUnavailable("down")
+	})
+	if !errors.Is(err, ErrAPIUnavailable) {
+		t.Errorf("callWithRetry() error = %v, expected %v", err, ErrAPIUnavailable)
+	}
+
+	// Permanent errors are returned as they are
+	err = callWithRetry(context.TODO(), Options{}, "list_pods", func(ctx context.Context) error {
+		return apierrors.NewForbidden(podsResource, "", errors.New("rbac"))
+	})
+	if errors.Is(err, ErrAPIUnavailable) || !apierrors.IsForbidden(err) {
+		t.Errorf("callWithRetry() error = %v, expected forbidden", err)
+	}
+}
+
+func TestRunMultusNotReady(t *testing.T) {
+	peer := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	peer.Namespace = "test-namespace"
+	self := createTestPodWithInvalidMultus("aeron-0", "10.0.0.1", time.Now())
+	self.Namespace = "test-namespace"
+	// Keep ourselves out of discovery, so the peer is found and our own network status is what fails
+	self.Labels = nil
+
+	opts := testOptions(fake.NewSimpleClientset(&peer, &self))
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+	opts.NetworkStatusTimeout = 100 * time.Millisecond
+
+	_, err := Run(context.TODO(), opts)
+	if !errors.Is(err, ErrMultusNotReady) {
+		t.Errorf("Run() error = %v, expected %v", err, ErrMultusNotReady)
+	}
+}
@@ -0,0 +1,188 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	BootstrapNeighborsAnnotation = "aeron.io/bootstrap-neighbors"
+	eventComponent               = "aeron-k8s-bootstrap"
+
+	// Event reasons recorded against the bootstrapping pod
+	eventReasonBootstrapped  = "Bootstrapped"
+	eventReasonNoNeighbors   = "NoBootstrapNeighbors"
+	eventReasonPodIPFallback = "PodIPFallback"
+	eventReasonPodsSkipped   = "PodsSkipped"
+
+	// The API server rejects event messages longer than this
+	maxEventMessageLength = 1024
+)
+
+// bootstrapEvents builds the Events describing a bootstrap result, in the order they should be recorded
+func bootstrapEvents(result Result) []v1.Event {
+	var events []v1.Event
+	newEvent := func(eventType, reason, message string) {
+		events = append(events, v1.Event{Type: eventType, Reason: reason, Message: truncateEventMessage(message)})
+	}
+
+	if len(result.Neighbors) == 0 {
+		newEvent(v1.EventTypeWarning, eventReasonNoNeighbors, "No suitable media driver pods found, bootstrap file not written")
+	} else {
+		var neighbors []string
+		for _, pod := range result.Neighbors {
+			neighbors = append(neighbors, fmt.Sprintf("%s (%s)", pod.Name, pod.Endpoint()))
+		}
+		newEvent(v1.EventTypeNormal, eventReasonBootstrapped, fmt.Sprintf("Bootstrapped as %s on %s with %d neighbors: %s",
+			result.ResolverName, result.ResolverInterface, len(result.Neighbors), strings.Join(neighbors, ", ")))
+	}
+
+	var fallbacks []string
+	if result.SelfIPSource == IPSourcePodIPFallback {
+		fallbacks = append(fallbacks, "self")
+	}
+	for _, pod := range result.Neighbors {
+		if pod.IPSource == IPSourcePodIPFallback {
+			fallbacks = append(fallbacks, pod.Name)
+		}
+	}
+	if len(fallbacks) > 0 {
+		newEvent(v1.EventTypeWarning, eventReasonPodIPFallback, fmt.Sprintf("No secondary network matched, fell back to status.PodIP for: %s",
+			strings.Join(fallbacks, ", ")))
+	}
+
+	if len(result.Skipped) > 0 {
+		var skipped []string
+		for _, skip := range result.Skipped {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", skip.Name, skip.Reason))
+		}
+		newEvent(v1.EventTypeWarning, eventReasonPodsSkipped, fmt.Sprintf("Skipped %d pods failing validation: %s",
+			len(result.Skipped), strings.Join(skipped, ", ")))
+	}
+
+	return events
+}
+
+// truncateEventMessage shortens a message to maxEventMessageLength bytes, cutting on a rune boundary
+func truncateEventMessage(message string) string {
+	if len(message) <= maxEventMessageLength {
+		return message
+	}
+	cut := maxEventMessageLength - len("...")
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + "..."
+}
+
+// recordBootstrapEvents records Events describing the bootstrap result against our own pod
+func recordBootstrapEvents(ctx context.Context, opts Options, pod v1.Pod, result Result) error {
+	for _, event := range bootstrapEvents(result) {
+		now := metav1.Now()
+		// The random suffix keeps events recorded within the same clock tick apart
+		event.ObjectMeta = metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x%s", pod.Name, now.UnixNano(), utilrand.String(5)),
+			Namespace: pod.Namespace,
+		}
+		event.InvolvedObject = v1.ObjectReference{
+			APIVersion:      "v1",
+			Kind:            "Pod",
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		}
+		event.Source = v1.EventSource{Component: eventComponent, Host: pod.Spec.NodeName}
+		event.FirstTimestamp = now
+		event.LastTimestamp = now
+		event.Count = 1
+		event.ReportingController = eventComponent
+		event.ReportingInstance = pod.Name
+
+		err := callWithRetry(ctx, opts, "create_event", func(ctx context.Context) error {
+			_, err := opts.Clientset.CoreV1().Events(pod.Namespace).Create(ctx, &event, metav1.CreateOptions{})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record %s event: %v", event.Reason, err)
+		}
+	}
+	return nil
+}
+
+// annotateBootstrapResult patches the chosen neighbor endpoints onto our own pod, so kubectl describe shows them
+func annotateBootstrapResult(ctx context.Context, opts Options, pod v1.Pod, result Result) error {
+	var neighbors []string
+	for _, neighbor := range result.Neighbors {
+		neighbors = append(neighbors, neighbor.Endpoint())
+	}
+	return patchPodAnnotations(ctx, opts, pod, map[string]string{
+		BootstrapNeighborsAnnotation: strings.Join(neighbors, ","),
+	})
+}
+
+// publishResolverIdentity patches our chosen resolver address, name and port onto our own pod,
+// so peers use what this driver actually advertises rather than re-deriving it
+func publishResolverIdentity(ctx context.Context, opts Options, pod v1.Pod, address, name string, port int) error {
+	return patchPodAnnotations(ctx, opts, pod, identityAnnotations(address, name, port))
+}
+
+// identityAnnotations returns the annotations publishing a resolver address, name and port
+func identityAnnotations(address, name string, port int) map[string]string {
+	return map[string]string{
+		PublishedAddressAnnotation: address,
+		PublishedNameAnnotation:    name,
+		PublishedPortAnnotation:    strconv.Itoa(port),
+	}
+}
+
+// identityPublished returns whether a pod already carries the given published resolver identity
+func identityPublished(pod v1.Pod, address, name string, port int) bool {
+	return dataUnchanged(pod.Annotations, identityAnnotations(address, name, port))
+}
+
+// patchPodAnnotations merges the given annotations into a pod's metadata
+func patchPodAnnotations(ctx context.Context, opts Options, pod v1.Pod, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build annotation patch: %v", err)
+	}
+
+	err = callWithRetry(ctx, opts, "patch_pod", func(ctx context.Context) error {
+		_, err := opts.Clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to annotate pod %s: %v", pod.Name, err)
+	}
+	return nil
+}
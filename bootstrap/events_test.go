@@ -0,0 +1,349 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBootstrapEvents(t *testing.T) {
+	tests := []struct {
+		name            string
+		result          Result
+		expectedReasons []string
+		expectedText    []string
+	}{
+		{
+			name: "neighbors chosen",
+			result: Result{
+				Neighbors:         []PodInfo{{Name: "aeron-0", IP: "10.0.0.1", Port: 8050, IPSource: IPSourcePodIP}},
+				ResolverName:      "aeron-1.test.aeron",
+				ResolverInterface: "10.0.0.2:8050",
+			},
+			expectedReasons: []string{eventReasonBootstrapped},
+			expectedText:    []string{"aeron-1.test.aeron", "aeron-0 (10.0.0.1:8050)"},
+		},
+		{
+			name:            "no neighbors",
+			result:          Result{},
+			expectedReasons: []string{eventReasonNoNeighbors},
+		},
+		{
+			name: "fallbacks and skips",
+			result: Result{
+				Neighbors: []PodInfo{{Name: "aeron-0", IP: "10.0.0.1", Port: 8050, IPSource: IPSourcePodIPFallback}},
+				Skipped: []PodSkip{
+					{Name: "aeron-2", Reason: SkipReasonMissingNetworkStatus},
+					{Name: "aeron-3", Reason: SkipReasonInvalidResolverName},
+				},
+				SelfIPSource: IPSourcePodIPFallback,
+			},
+			expectedReasons: []string{eventReasonBootstrapped, eventReasonPodIPFallback, eventReasonPodsSkipped},
+			expectedText:    []string{"self, aeron-0", "Skipped 2 pods", "aeron-2 (MissingNetworkStatus), aeron-3 (InvalidResolverName)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := bootstrapEvents(tt.result)
+			if len(events) != len(tt.expectedReasons) {
+				t.Fatalf("bootstrapEvents() returned %d events, expected %d", len(events), len(tt.expectedReasons))
+			}
+
+			var messages []string
+			for i, event := range events {
+				if event.Reason != tt.expectedReasons[i] {
+					t.Errorf("Event %d reason = %s, expected %s", i, event.Reason, tt.expectedReasons[i])
+				}
+				messages = append(messages, event.Message)
+			}
+
+			allMessages := strings.Join(messages, "\n")
+			for _, text := range tt.expectedText {
+				if !strings.Contains(allMessages, text) {
+					t.Errorf("Expected event messages to contain %q, got:\n%s", text, allMessages)
+				}
+			}
+		})
+	}
+}
+
+func TestBootstrapEventsTruncatesLongMessages(t *testing.T) {
+	var neighbors []PodInfo
+	for i := 0; i < 100; i++ {
+		neighbors = append(neighbors, PodInfo{Name: "aeron-media-driver-with-a-long-name", IP: "10.0.0.1", Port: 8050})
+	}
+
+	events := bootstrapEvents(Result{Neighbors: neighbors})
+	if len(events[0].Message) != maxEventMessageLength {
+		t.Errorf("Event message length = %d, expected %d", len(events[0].Message), maxEventMessageLength)
+	}
+}
+
+func TestTruncateEventMessageOnRuneBoundary(t *testing.T) {
+	// The cut falls inside the two byte é, which must not be split
+	message := strings.Repeat("a", maxEventMessageLength-4) + strings.Repeat("é", 10)
+	truncated := truncateEventMessage(message)
+	if !utf8.ValidString(truncated) || len(truncated) > maxEventMessageLength || !strings.HasSuffix(truncated, "...") {
+		t.Errorf("truncateEventMessage() = %q (%d bytes), expected valid UTF-8 of at most %d bytes ending ...",
+			truncated[len(truncated)-8:], len(truncated), maxEventMessageLength)
+	}
+	if short := "Bootstrapped é"; truncateEventMessage(short) != short {
+		t.Errorf("truncateEventMessage(%q) = %q, expected it unchanged", short, truncateEventMessage(short))
+	}
+}
+
+func TestRecordBootstrapEvents(t *testing.T) {
+	pod := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now())
+	pod.Namespace = "test-namespace"
+	clientset := fake.NewSimpleClientset(&pod)
+
+	result := Result{
+		Neighbors: []PodInfo{{Name: "aeron-0", IP: "10.0.0.1", Port: 8050}},
+		Skipped:   []PodSkip{{Name: "aeron-2", Reason: SkipReasonNetworkNotInStatus}},
+	}
+	if err := recordBootstrapEvents(context.TODO(), testOptions(clientset), pod, result); err != nil {
+		t.Fatalf("recordBootstrapEvents() error = %v", err)
+	}
+
+	events, err := clientset.CoreV1().Events("test-namespace").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(events.Items) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events.Items))
+	}
+	if events.Items[0].Name == events.Items[1].Name {
+		t.Errorf("Expected distinct event names, got %s twice", events.Items[0].Name)
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind != "Pod" || event.InvolvedObject.Name != "aeron-1" {
+			t.Errorf("Event involved object = %s/%s, expected Pod/aeron-1", event.InvolvedObject.Kind, event.InvolvedObject.Name)
+		}
+		if event.Source.Component != eventComponent {
+			t.Errorf("Event source = %s, expected %s", event.Source.Component, eventComponent)
+		}
+	}
+}
+
+func TestAnnotateBootstrapResult(t *testing.T) {
+	pod := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now())
+	pod.Namespace = "test-namespace"
+	clientset := fake.NewSimpleClientset(&pod)
+
+	result := Result{
+		Neighbors: []PodInfo{
+			{Name: "aeron-0", IP: "10.0.0.1", Port: 8050},
+			{Name: "aeron-1", IP: "10.0.0.2", Port: 9050},
+		},
+	}
+	if err := annotateBootstrapResult(context.TODO(), testOptions(clientset), pod, result); err != nil {
+		t.Fatalf("annotateBootstrapResult() error = %v", err)
+	}
+
+	annotated, err := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	if got := annotated.Annotations[BootstrapNeighborsAnnotation]; got != "10.0.0.1:8050,10.0.0.2:9050" {
+		t.Errorf("%s = %q, expected %q", BootstrapNeighborsAnnotation, got, "10.0.0.1:8050,10.0.0.2:9050")
+	}
+	if annotated.Labels["aeron.io/media-driver"] != "true" {
+		t.Errorf("Expected existing labels to be preserved by the patch")
+	}
+}
+
+func TestPublishResolverIdentity(t *testing.T) {
+	pod := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now())
+	pod.Namespace = "test-namespace"
+	clientset := fake.NewSimpleClientset(&pod)
+
+	if err := publishResolverIdentity(context.TODO(), testOptions(clientset), pod, "192.168.1.200", "aeron-1.test-namespace.aeron", 8050); err != nil {
+		t.Fatalf("publishResolverIdentity() error = %v", err)
+	}
+
+	result, err := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+
+	expected := map[string]string{
+		PublishedAddressAnnotation: "192.168.1.200",
+		PublishedNameAnnotation:    "aeron-1.test-namespace.aeron",
+		PublishedPortAnnotation:    "8050",
+	}
+	for key, value := range expected {
+		if result.Annotations[key] != value {
+			t.Errorf("%s = %q, expected %q", key, result.Annotations[key], value)
+		}
+	}
+	// The overrides are left to operators, so they never pin the published identity
+	for _, key := range []string{ResolverAddressAnnotation, ResolverNameAnnotation, ResolverPortAnnotation} {
+		if value, ok := result.Annotations[key]; ok {
+			t.Errorf("%s = %q, expected it not to be set", key, value)
+		}
+	}
+	if !identityPublished(*result, "192.168.1.200", "aeron-1.test-namespace.aeron", 8050) {
+		t.Errorf("Expected identityPublished() after publishing")
+	}
+
+	// What was published is what peers now discover
+	if ip, _ := PublishedIP(*result); ip != "192.168.1.200" {
+		t.Errorf("PublishedIP() after publishing = %s, expected 192.168.1.200", ip)
+	}
+}
+
+func TestDiscoverPodsReportsSkips(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	pods := []corev1.Pod{
+		createTestPodWithMultus("aeron-valid", "10.0.0.1", "mynet", "10.0.0.2", time.Now().Add(-5*time.Minute)),
+		createTestPodWithInvalidMultus("aeron-invalid", "10.0.0.3", time.Now().Add(-3*time.Minute)),
+	}
+	for _, pod := range pods {
+		_, err := clientset.CoreV1().Pods("test-namespace").Create(context.TODO(), &pod, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Failed to create test pod: %v", err)
+		}
+	}
+
+	result, skipped, err := DiscoverPods(context.TODO(), testOptions(clientset))
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	if len(result) != 1 || result[0].IPSource != IPSourceDefaultInterface {
+		t.Errorf("Expected the valid pod via %s, got %+v", IPSourceDefaultInterface, result)
+	}
+	if len(skipped) != 1 || skipped[0].Name != "aeron-invalid" || skipped[0].Reason != SkipReasonMissingNetworkStatus {
+		t.Errorf("Expected aeron-invalid skipped with %s, got %+v", SkipReasonMissingNetworkStatus, skipped)
+	}
+}
+
+func TestRunRepublishesIdentity(t *testing.T) {
+	// A stale published name, e.g. from before a suffix change, is neither reused nor kept
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	self.Namespace = "test-namespace"
+	self.Annotations = map[string]string{PublishedNameAnnotation: "aeron-0.stale.aeron"}
+	clientset := fake.NewSimpleClientset(&self)
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.EmitEvents = false
+
+	published := func() map[string]string {
+		pod, err := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-0", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get pod: %v", err)
+		}
+		return pod.Annotations
+	}
+	patches := func() int {
+		count := 0
+		for _, action := range clientset.Actions() {
+			if action.GetVerb() == "patch" {
+				count++
+			}
+		}
+		return count
+	}
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written || result.ResolverName != "aeron-0.test-namespace.aeron" {
+		t.Fatalf("Run() = (%v, %s, %v), expected (true, aeron-0.test-namespace.aeron, nil)", result.Written, result.ResolverName, err)
+	}
+	if name := published()[PublishedNameAnnotation]; name != "aeron-0.test-namespace.aeron" {
+		t.Errorf("%s = %q, expected aeron-0.test-namespace.aeron", PublishedNameAnnotation, name)
+	}
+
+	// Losing the published identity republishes it, though the file is unchanged
+	pod, _ := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-0", metav1.GetOptions{})
+	pod.Annotations = nil
+	if _, err := clientset.CoreV1().Pods("test-namespace").Update(context.TODO(), pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update pod: %v", err)
+	}
+	result, err = Run(context.TODO(), opts)
+	if err != nil || result.Written {
+		t.Fatalf("Unchanged Run() = (%v, %v), expected (false, nil)", result.Written, err)
+	}
+	if address := published()[PublishedAddressAnnotation]; address != "10.0.0.1" {
+		t.Errorf("%s = %q after republishing, expected 10.0.0.1", PublishedAddressAnnotation, address)
+	}
+
+	// Once published, an unchanged refresh doesn't patch the pod
+	before := patches()
+	if _, err := Run(context.TODO(), opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if after := patches(); after != before {
+		t.Errorf("Expected no patches on an unchanged refresh, got %d", after-before)
+	}
+}
+
+func TestRunHonoursResolverAddressOverride(t *testing.T) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	self.Namespace = "test-namespace"
+	self.Annotations = map[string]string{ResolverAddressAnnotation: "192.168.9.9"}
+	peer := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	peer.Namespace = "test-namespace"
+	clientset := fake.NewSimpleClientset(&self, &peer)
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.EmitEvents = false
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ResolverInterface != "192.168.9.9:8050" {
+		t.Errorf("ResolverInterface = %s, expected the overridden 192.168.9.9:8050", result.ResolverInterface)
+	}
+	pod, err := clientset.CoreV1().Pods("test-namespace").Get(context.TODO(), "aeron-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	if address := pod.Annotations[PublishedAddressAnnotation]; address != "192.168.9.9" {
+		t.Errorf("%s = %q, expected the override", PublishedAddressAnnotation, address)
+	}
+
+	// A peer reaches us at the override we published and bound
+	peerOpts := testOptions(clientset)
+	peerOpts.PodName = "aeron-1"
+	pods, _, err := DiscoverPods(context.TODO(), peerOpts)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	ip := ""
+	for _, pod := range pods {
+		if pod.Name == "aeron-0" {
+			ip = pod.IP
+		}
+	}
+	if ip != "192.168.9.9" {
+		t.Errorf("Peer discovered aeron-0 at %q, expected 192.168.9.9", ip)
+	}
+}
@@ -0,0 +1,169 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// Results of Multus validation reported by Explain
+const (
+	MultusNotRequested = "NotRequested"
+	MultusValid        = "Valid"
+)
+
+// Reasons reported by Explain for pods that pass Multus validation, alongside the SkipReason constants
+const (
+	ExplainReasonSelected          = "Selected"
+	ExplainReasonNoIP              = "NoIP"
+	ExplainReasonIPSelectionFailed = "IPSelectionFailed"
+	ExplainReasonMaxPods           = "BeyondMaxPods"
+	// With Options.Ordinal set, pods that aren't our own neighbors in the topology, and members predicted from DNS
+	ExplainReasonNotOrdinalNeighbor = "NotOrdinalNeighbor"
+	ExplainReasonPredicted          = "Predicted"
+)
+
+// PodDecision explains whether a candidate pod was used as a bootstrap neighbor, and why
+type PodDecision struct {
+	Name     string    `json:"name"`
+	Phase    string    `json:"phase"`
+	IP       string    `json:"ip,omitempty"`
+	IPSource string    `json:"ipSource,omitempty"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Multus   string    `json:"multus"`
+	Included bool      `json:"included"`
+	Reason   string    `json:"reason"`
+	Detail   string    `json:"detail,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+// Explain evaluates every pod matching opts.LabelSelector the way DiscoverPods does, or DiscoverOrdinalPeers with
+// opts.Ordinal set, returning a decision for each, sorted from oldest to newest, followed by any predicted members
+func Explain(ctx context.Context, opts Options) ([]PodDecision, error) {
+	pods, err := listCandidatePods(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decisions := make(map[string]*PodDecision, len(pods))
+	var candidates []PodInfo
+	var result []*PodDecision
+	nodes := nodeCache{}
+	for _, pod := range pods {
+		decision := &PodDecision{
+			Name:    pod.Name,
+			Phase:   string(pod.Status.Phase),
+			Multus:  multusResult(pod),
+			Created: pod.CreationTimestamp.Time,
+		}
+		decisions[pod.Name] = decision
+		result = append(result, decision)
+
+		podInfo, skip, err := evaluatePod(ctx, pod, opts, nodes)
+		switch {
+		case err != nil:
+			decision.Reason, decision.Detail = ExplainReasonIPSelectionFailed, err.Error()
+		case skip != nil:
+			decision.Reason, decision.Detail = skip.Reason, skip.Detail
+		case podInfo.IP == "":
+			decision.Reason, decision.Detail = ExplainReasonNoIP, "has no IP address yet"
+		default:
+			decision.IP, decision.IPSource, decision.Endpoint = podInfo.IP, podInfo.IPSource, podInfo.Endpoint()
+			decision.Reason = ExplainReasonMaxPods
+			decision.Detail = fmt.Sprintf("only the oldest %d pods are used", opts.MaxPods)
+			candidates = append(candidates, podInfo)
+		}
+	}
+
+	var predicted []PodDecision
+	if opts.Ordinal != "" {
+		if predicted, err = explainOrdinal(ctx, opts, decisions, candidates); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, podInfo := range oldestPods(candidates, opts.MaxPods) {
+			decision := decisions[podInfo.Name]
+			decision.Included = true
+			decision.Reason, decision.Detail = ExplainReasonSelected, ""
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	explained := make([]PodDecision, 0, len(result)+len(predicted))
+	for _, decision := range result {
+		explained = append(explained, *decision)
+	}
+	return append(explained, predicted...), nil
+}
+
+// explainOrdinal marks the candidates DiscoverOrdinalPeers chooses as our own neighbors in the opts.Ordinal topology,
+// returning a decision for each neighbor it predicts from the StatefulSet's DNS names
+func explainOrdinal(ctx context.Context, opts Options, decisions map[string]*PodDecision, candidates []PodInfo) ([]PodDecision, error) {
+	currentPod, err := CurrentPod(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	neighbors, _, err := DiscoverOrdinalPeers(ctx, opts, currentPod)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, podInfo := range candidates {
+		decision := decisions[podInfo.Name]
+		decision.Reason = ExplainReasonNotOrdinalNeighbor
+		decision.Detail = fmt.Sprintf("not a neighbor of %s in the %s ordinal topology", currentPod.Name, opts.Ordinal)
+	}
+	var predicted []PodDecision
+	for _, neighbor := range neighbors {
+		if neighbor.IPSource == IPSourceStatefulSetDNS {
+			predicted = append(predicted, PodDecision{
+				Name:     neighbor.Name,
+				IP:       neighbor.IP,
+				IPSource: neighbor.IPSource,
+				Endpoint: neighbor.Endpoint(),
+				Multus:   MultusNotRequested,
+				Included: true,
+				Reason:   ExplainReasonPredicted,
+				Detail:   "has no address yet, so is reached at its stable DNS name",
+			})
+			continue
+		}
+		if decision, ok := decisions[neighbor.Name]; ok {
+			decision.Included = true
+			decision.Reason, decision.Detail = ExplainReasonSelected, ""
+		}
+	}
+	return predicted, nil
+}
+
+// multusResult summarises a pod's Multus validation for Explain
+func multusResult(pod v1.Pod) string {
+	if pod.Annotations[NetworksAnnotation] == "" {
+		return MultusNotRequested
+	}
+	if skip := CheckMultusNetworkStatus(pod); skip != nil {
+		return skip.Reason
+	}
+	return MultusValid
+}
@@ -0,0 +1,138 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExplain(t *testing.T) {
+	now := time.Now()
+	pods := []corev1.Pod{
+		createTestPodWithMultus("aeron-0", "10.0.0.1", "mynet", "192.168.1.1", now.Add(-50*time.Minute)),
+		createTestPod("aeron-1", "10.0.0.2", "Running", now.Add(-40*time.Minute)),
+		createTestPodWithInvalidMultus("aeron-2", "10.0.0.3", now.Add(-30*time.Minute)),
+		createTestPodWithoutIP("aeron-3", "Pending", now.Add(-20*time.Minute)),
+		createTestPod("aeron-4", "10.0.0.5", "Running", now.Add(-10*time.Minute)),
+	}
+	var objects []runtime.Object
+	for i := range pods {
+		pods[i].Namespace = "test-namespace"
+		objects = append(objects, &pods[i])
+	}
+
+	opts := testOptions(fake.NewSimpleClientset(objects...))
+	opts.MaxPods = 2
+
+	decisions, err := Explain(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+
+	expected := []struct {
+		name     string
+		ipSource string
+		multus   string
+		included bool
+		reason   string
+	}{
+		{"aeron-0", IPSourceDefaultInterface, MultusValid, true, ExplainReasonSelected},
+		{"aeron-1", IPSourcePodIP, MultusNotRequested, true, ExplainReasonSelected},
+		{"aeron-2", "", SkipReasonMissingNetworkStatus, false, SkipReasonMissingNetworkStatus},
+		{"aeron-3", "", MultusNotRequested, false, ExplainReasonNoIP},
+		{"aeron-4", IPSourcePodIP, MultusNotRequested, false, ExplainReasonMaxPods},
+	}
+	if len(decisions) != len(expected) {
+		t.Fatalf("Explain() returned %d decisions, expected %d: %+v", len(decisions), len(expected), decisions)
+	}
+	for i, e := range expected {
+		d := decisions[i]
+		if d.Name != e.name || d.IPSource != e.ipSource || d.Multus != e.multus || d.Included != e.included || d.Reason != e.reason {
+			t.Errorf("Decision %d = %+v, expected %+v", i, d, e)
+		}
+	}
+
+	// Explain agrees with the neighbors DiscoverPods selects
+	neighbors, _, err := DiscoverPods(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	if len(neighbors) != 2 || neighbors[0].Name != "aeron-0" || neighbors[1].Name != "aeron-1" {
+		t.Errorf("DiscoverPods() = %+v, expected aeron-0 and aeron-1", neighbors)
+	}
+}
+
+func TestExplainOrdinal(t *testing.T) {
+	now := time.Now()
+	objects := []runtime.Object{createTestStatefulSet("aeron-headless", 0)}
+	for i, ordinal := range []int{0, 2, 3} {
+		pod := createStatefulSetPod("aeron", ordinal, "", now.Add(time.Duration(i)*time.Minute))
+		objects = append(objects, &pod)
+	}
+	opts := testOptions(fake.NewSimpleClientset(objects...))
+	opts.PodName = "aeron-2"
+	opts.Ordinal = OrdinalPredecessors
+	opts.MaxPods = 2
+
+	decisions, err := Explain(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Explain() error = %v", err)
+	}
+
+	// aeron-1 was never created, so is predicted from its DNS name, and aeron-2 is ourselves rather than the
+	// second oldest pod selected by age
+	expected := []struct {
+		name     string
+		ip       string
+		ipSource string
+		included bool
+		reason   string
+	}{
+		{"aeron-0", "10.0.0.1", IPSourcePodIP, true, ExplainReasonSelected},
+		{"aeron-2", "10.0.0.3", IPSourcePodIP, false, ExplainReasonNotOrdinalNeighbor},
+		{"aeron-3", "10.0.0.4", IPSourcePodIP, false, ExplainReasonNotOrdinalNeighbor},
+		{"aeron-1", "aeron-1.aeron-headless.test-namespace.svc.cluster.local", IPSourceStatefulSetDNS, true, ExplainReasonPredicted},
+	}
+	if len(decisions) != len(expected) {
+		t.Fatalf("Explain() returned %d decisions, expected %d: %+v", len(decisions), len(expected), decisions)
+	}
+	for i, e := range expected {
+		d := decisions[i]
+		if d.Name != e.name || d.IP != e.ip || d.IPSource != e.ipSource || d.Included != e.included || d.Reason != e.reason {
+			t.Errorf("Decision %d = %+v, expected %+v", i, d, e)
+		}
+	}
+
+	// Explain agrees with the neighbors DiscoverOrdinalPeers selects
+	currentPod, err := CurrentPod(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("CurrentPod() error = %v", err)
+	}
+	neighbors, _, err := DiscoverOrdinalPeers(context.TODO(), opts, currentPod)
+	if err != nil {
+		t.Fatalf("DiscoverOrdinalPeers() error = %v", err)
+	}
+	if len(neighbors) != 2 || neighbors[0].Name != "aeron-0" || neighbors[1].Name != "aeron-1" {
+		t.Errorf("DiscoverOrdinalPeers() = %+v, expected aeron-0 and aeron-1", neighbors)
+	}
+}
@@ -0,0 +1,427 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The AeronMediaDriverFabric custom resource, declared by examples/fabric.yml
+const (
+	FabricGroup    = "aeron.io"
+	FabricVersion  = "v1alpha1"
+	FabricKind     = "AeronMediaDriverFabric"
+	FabricResource = "aeronmediadriverfabrics"
+)
+
+// FabricGVR identifies AeronMediaDriverFabric resources for the dynamic client
+var FabricGVR = schema.GroupVersionResource{Group: FabricGroup, Version: FabricVersion, Resource: FabricResource}
+
+// Fabric is an AeronMediaDriverFabric, declaring a set of media drivers and how they bootstrap against each other
+type Fabric struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FabricSpec   `json:"spec"`
+	Status FabricStatus `json:"status,omitempty"`
+}
+
+// FabricSpec declares the media driver pods of a fabric and how their neighbors are chosen and published
+type FabricSpec struct {
+	// Selector selects the media driver pods, defaulting to DefaultLabelSelector
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// Namespaces holding the media driver pods, defaulting to the fabric's own namespace
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Network selects the Multus network each pod's address is taken from
+	Network FabricNetwork `json:"network,omitempty"`
+	// DiscoveryPort is the resolver port used for pods that don't advertise their own, defaulting to DefaultDiscoveryPort
+	DiscoveryPort int `json:"discoveryPort,omitempty"`
+	// HostnameSuffix is appended to <pod>.<namespace> to build resolver names, defaulting to DefaultHostnameSuffix
+	HostnameSuffix string `json:"hostnameSuffix,omitempty"`
+	// Strategy chooses each pod's neighbors, one of the Strategy constants, defaulting to StrategyOldest
+	Strategy string `json:"strategy,omitempty"`
+	// MaxNeighbors limits each pod's neighbors, see AssignNeighbors
+	MaxNeighbors int `json:"maxNeighbors,omitempty"`
+	// Output selects where each pod's assignment is published
+	Output FabricOutput `json:"output,omitempty"`
+}
+
+// FabricNetwork selects the Multus network each pod's address is taken from
+type FabricNetwork struct {
+	NetworkName   string `json:"networkName,omitempty"`
+	InterfaceName string `json:"interfaceName,omitempty"`
+	IPSelection   string `json:"ipSelection,omitempty"`
+}
+
+// FabricOutput selects where each pod's assignment is published
+type FabricOutput struct {
+	// Type is one of the Assignment constants, defaulting to AssignmentConfigMap
+	Type string `json:"type,omitempty"`
+	// FabricConfigMap, if set, names a ConfigMap in the fabric's namespace describing every member
+	FabricConfigMap string `json:"fabricConfigMap,omitempty"`
+}
+
+// FabricStatus reports the outcome of the last reconcile of a fabric
+type FabricStatus struct {
+	ObservedGeneration int64                `json:"observedGeneration,omitempty"`
+	Members            int                  `json:"members"`
+	UnhealthyMembers   int                  `json:"unhealthyMembers"`
+	Unhealthy          []FabricUnhealthyPod `json:"unhealthy,omitempty"`
+	LastReconcileTime  *metav1.Time         `json:"lastReconcileTime,omitempty"`
+	Error              string               `json:"error,omitempty"`
+}
+
+// SkipReasonSelectedByOtherFabric reports a pod also selected by an older fabric, which keeps it as a member
+const SkipReasonSelectedByOtherFabric = "SelectedByOtherFabric"
+
+// FabricUnhealthyPod is a pod selected by a fabric that could not be made a member
+type FabricUnhealthyPod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// FabricFromUnstructured converts an AeronMediaDriverFabric read by the dynamic client
+func FabricFromUnstructured(obj *unstructured.Unstructured) (Fabric, error) {
+	var fabric Fabric
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &fabric); err != nil {
+		return Fabric{}, fmt.Errorf("invalid %s %s/%s: %v", FabricKind, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return fabric, nil
+}
+
+// fabricNamespaces returns the namespaces holding a fabric's pods
+func fabricNamespaces(fabric Fabric) []string {
+	if len(fabric.Spec.Namespaces) > 0 {
+		return fabric.Spec.Namespaces
+	}
+	return []string{fabric.Namespace}
+}
+
+// fabricSelector returns the label selector of a fabric's pods
+func fabricSelector(fabric Fabric) (labels.Selector, error) {
+	if fabric.Spec.Selector == nil {
+		return labels.Parse(DefaultLabelSelector)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(fabric.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	return selector, nil
+}
+
+// fabricSelects returns whether a fabric selects pod by its namespaces and selector, an invalid selector selecting nothing
+func fabricSelects(fabric Fabric, pod v1.Pod) bool {
+	selector, err := fabricSelector(fabric)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(fabricNamespaces(fabric), pod.Namespace) && selector.Matches(labels.Set(pod.Labels))
+}
+
+// fabricControllerOptions builds the controller options of a fabric from its spec, applying the defaults
+func fabricControllerOptions(clientset kubernetes.Interface, fabric Fabric) ControllerOptions {
+	opts := DefaultOptions()
+	opts.Clientset = clientset
+	opts.Namespace = fabric.Namespace
+	opts.MaxPods = fabric.Spec.MaxNeighbors
+	opts.SecondaryInterface = SecondaryInterface(fabric.Spec.Network)
+	if fabric.Spec.DiscoveryPort > 0 {
+		opts.DiscoveryPort = fabric.Spec.DiscoveryPort
+	}
+	if fabric.Spec.HostnameSuffix != "" {
+		opts.HostnameSuffix = fabric.Spec.HostnameSuffix
+	}
+
+	copts := ControllerOptions{
+		Options:         opts,
+		Strategy:        fabric.Spec.Strategy,
+		Publish:         fabric.Spec.Output.Type,
+		FabricConfigMap: fabric.Spec.Output.FabricConfigMap,
+	}
+	if copts.Strategy == "" {
+		copts.Strategy = StrategyOldest
+	}
+	if copts.Publish == "" {
+		copts.Publish = AssignmentConfigMap
+	}
+	if fabric.UID != "" {
+		copts.FabricOwners = []metav1.OwnerReference{{
+			APIVersion: FabricGroup + "/" + FabricVersion,
+			Kind:       FabricKind,
+			Name:       fabric.Name,
+			UID:        fabric.UID,
+		}}
+	}
+	return copts
+}
+
+// ReconcileFabric assigns and publishes neighbors for the pods of a fabric, chosen from pods by the fabric's
+// namespaces and selector, returning the fabric's new status
+func ReconcileFabric(ctx context.Context, clientset kubernetes.Interface, fabric Fabric, pods []v1.Pod, now time.Time) FabricStatus {
+	status := FabricStatus{ObservedGeneration: fabric.Generation, LastReconcileTime: &metav1.Time{Time: now}}
+
+	if _, err := fabricSelector(fabric); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	var selected []v1.Pod
+	for _, pod := range pods {
+		if fabricSelects(fabric, pod) {
+			selected = append(selected, pod)
+		}
+	}
+
+	copts := fabricControllerOptions(clientset, fabric)
+	members, skipped, err := Reconcile(ctx, copts, selected)
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	status.Members = len(members)
+	status.UnhealthyMembers = len(skipped)
+	for _, skip := range skipped {
+		status.Unhealthy = append(status.Unhealthy, FabricUnhealthyPod{
+			Name: skip.Name, Namespace: skip.Namespace, Reason: skip.Reason, Detail: skip.Detail,
+		})
+	}
+	return status
+}
+
+// updateFabricStatus writes a fabric's status through the status subresource
+func updateFabricStatus(ctx context.Context, client dynamic.Interface, obj *unstructured.Unstructured, status FabricStatus) error {
+	statusObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&status)
+	if err != nil {
+		return fmt.Errorf("failed to convert status of %s %s: %v", FabricKind, obj.GetName(), err)
+	}
+	updated := obj.DeepCopy()
+	if err := unstructured.SetNestedField(updated.Object, statusObject, "status"); err != nil {
+		return fmt.Errorf("failed to set status of %s %s: %v", FabricKind, obj.GetName(), err)
+	}
+	_, err = client.Resource(FabricGVR).Namespace(obj.GetNamespace()).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update status of %s %s: %w", FabricKind, obj.GetName(), err)
+	}
+	return nil
+}
+
+// FabricControllerOptions configures RunFabricController
+type FabricControllerOptions struct {
+	Clientset kubernetes.Interface
+	Dynamic   dynamic.Interface
+	// Namespace to watch for fabrics, empty meaning every namespace
+	Namespace string
+	// PodSelector limits the pods cached by the controller, empty caching every pod in the cluster
+	// Fabrics only see the pods it selects, so their selectors must narrow it
+	PodSelector string
+	// ResyncPeriod re-reconciles every fabric periodically even without changes, 0 disables it
+	ResyncPeriod time.Duration
+}
+
+// RunFabricController watches AeronMediaDriverFabrics and pods, reconciling every fabric and reporting its status
+// whenever either changes, until ctx is done
+// Only one controller should run at a time, e.g. under leader election
+func RunFabricController(ctx context.Context, fopts FabricControllerOptions) error {
+	fabricFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(fopts.Dynamic, fopts.ResyncPeriod, fopts.Namespace, nil)
+	fabricInformer := fabricFactory.ForResource(FabricGVR)
+	// Fabrics may select pods in any namespace, so only their labels narrow the cache
+	podFactory := informers.NewSharedInformerFactoryWithOptions(fopts.Clientset, fopts.ResyncPeriod,
+		informers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = fopts.PodSelector
+		}))
+	podInformer := podFactory.Core().V1().Pods()
+
+	// Coalesce bursts of changes into a single reconcile
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+	_, err := fabricInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(any) { notify() },
+		// Ignore our own status updates, which leave the generation unchanged, unless resyncing
+		UpdateFunc: func(oldObj, newObj any) {
+			oldFabric, oldOK := oldObj.(*unstructured.Unstructured)
+			newFabric, newOK := newObj.(*unstructured.Unstructured)
+			if !oldOK || !newOK || oldFabric.GetGeneration() != newFabric.GetGeneration() ||
+				oldFabric.GetResourceVersion() == newFabric.GetResourceVersion() {
+				notify()
+			}
+		},
+		DeleteFunc: func(any) { notify() },
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", FabricResource, err)
+	}
+	_, err = podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(any) { notify() },
+		UpdateFunc: func(any, any) { notify() },
+		DeleteFunc: func(any) { notify() },
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch pods: %w", err)
+	}
+
+	fabricFactory.Start(ctx.Done())
+	podFactory.Start(ctx.Done())
+	defer fabricFactory.Shutdown()
+	defer podFactory.Shutdown()
+	if !cache.WaitForCacheSync(ctx.Done(), fabricInformer.Informer().HasSynced, podInformer.Informer().HasSynced) {
+		return fmt.Errorf("%w: caches never synced: %v", ErrAPIUnavailable, ctx.Err())
+	}
+	slog.Info("Watching media driver fabrics", LogKeyNamespace, fopts.Namespace)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+			reconcileFabrics(ctx, fopts, fabricInformer.Lister(), podInformer.Lister())
+		}
+	}
+}
+
+// reconcileFabrics reconciles every cached fabric against the cached pods, updating the status of each
+func reconcileFabrics(ctx context.Context, fopts FabricControllerOptions, fabrics cache.GenericLister, podLister corelisters.PodLister) {
+	objects, err := fabrics.List(labels.Everything())
+	if err != nil {
+		slog.Warn("Failed to list cached fabrics", "error", err)
+		return
+	}
+	cached, err := podLister.List(labels.Everything())
+	if err != nil {
+		slog.Warn("Failed to list cached pods", "error", err)
+		return
+	}
+	pods := make([]v1.Pod, 0, len(cached))
+	for _, pod := range cached {
+		pods = append(pods, *pod)
+	}
+	reconcileFabricObjects(ctx, fopts, objects, pods)
+}
+
+// reconcileFabricObjects reconciles each fabric against pods, updating the status of each
+// A pod selected by several fabrics is left to the oldest, and reported as unhealthy by the others
+func reconcileFabricObjects(ctx context.Context, fopts FabricControllerOptions, objects []runtime.Object, pods []v1.Pod) {
+	var valid []Fabric
+	statuses := make(map[string]FabricStatus, len(objects))
+	for _, object := range objects {
+		obj, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		fabric, err := FabricFromUnstructured(obj)
+		if err != nil {
+			statuses[obj.GetNamespace()+"/"+obj.GetName()] = FabricStatus{ObservedGeneration: obj.GetGeneration(), LastReconcileTime: &metav1.Time{Time: time.Now()}, Error: err.Error()}
+			continue
+		}
+		valid = append(valid, fabric)
+	}
+
+	claims := claimFabricPods(valid, pods)
+	for _, fabric := range valid {
+		owned := make([]v1.Pod, 0, len(pods))
+		var conflicts []FabricUnhealthyPod
+		for _, pod := range pods {
+			owner, claimed := claims[podKey(pod)]
+			if !claimed || fabricKey(owner) == fabricKey(fabric) {
+				owned = append(owned, pod)
+				continue
+			}
+			if fabricSelects(fabric, pod) {
+				conflicts = append(conflicts, FabricUnhealthyPod{
+					Name: pod.Name, Namespace: pod.Namespace, Reason: SkipReasonSelectedByOtherFabric,
+					Detail: fmt.Sprintf("already a member of %s %s/%s", FabricKind, owner.Namespace, owner.Name),
+				})
+			}
+		}
+
+		status := ReconcileFabric(ctx, fopts.Clientset, fabric, owned, time.Now())
+		status.Unhealthy = append(status.Unhealthy, conflicts...)
+		status.UnhealthyMembers += len(conflicts)
+		statuses[fabricKey(fabric)] = status
+	}
+
+	for _, object := range objects {
+		obj, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		status := statuses[obj.GetNamespace()+"/"+obj.GetName()]
+		if status.Error != "" {
+			slog.Warn("Failed to reconcile fabric", "fabric", obj.GetName(), LogKeyNamespace, obj.GetNamespace(), "error", status.Error)
+		}
+		// The informer ignores this status update, as it leaves the generation unchanged
+		if err := updateFabricStatus(ctx, fopts.Dynamic, obj, status); err != nil {
+			slog.Warn("Failed to update fabric status", "fabric", obj.GetName(), LogKeyNamespace, obj.GetNamespace(), "error", err)
+		}
+	}
+}
+
+// claimFabricPods returns the fabric owning each selected pod, keyed by podKey: the oldest fabric selecting it,
+// so a newer fabric overlapping an existing one never takes over its members
+func claimFabricPods(fabrics []Fabric, pods []v1.Pod) map[string]Fabric {
+	ordered := slices.Clone(fabrics)
+	slices.SortStableFunc(ordered, func(a, b Fabric) int {
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(fabricKey(a), fabricKey(b))
+	})
+
+	claims := make(map[string]Fabric)
+	for _, fabric := range ordered {
+		for _, pod := range pods {
+			key := podKey(pod)
+			if _, claimed := claims[key]; !claimed && fabricSelects(fabric, pod) {
+				claims[key] = fabric
+			}
+		}
+	}
+	return claims
+}
+
+// fabricKey identifies a fabric across namespaces
+func fabricKey(fabric Fabric) string {
+	return fabric.Namespace + "/" + fabric.Name
+}
+
+// podKey identifies a pod across namespaces
+func podKey(pod v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
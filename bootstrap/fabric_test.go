@@ -0,0 +1,224 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testFabric returns an AeronMediaDriverFabric in test-namespace spanning test-namespace and other-namespace
+func testFabric() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": FabricGroup + "/" + FabricVersion,
+		"kind":       FabricKind,
+		"metadata": map[string]any{
+			"name":       "fabric",
+			"namespace":  "test-namespace",
+			"uid":        "uid-fabric",
+			"generation": int64(3),
+		},
+		"spec": map[string]any{
+			"selector":      map[string]any{"matchLabels": map[string]any{"aeron.io/media-driver": "true"}},
+			"namespaces":    []any{"test-namespace", "other-namespace"},
+			"discoveryPort": int64(9050),
+			"strategy":      StrategyRing,
+			"maxNeighbors":  int64(1),
+			"output":        map[string]any{"type": AssignmentAnnotation},
+		},
+	}}
+}
+
+// fabricTestPods returns two healthy pods in different namespaces, one pod without an IP,
+// and pods outside the fabric's namespaces and selector
+func fabricTestPods() []corev1.Pod {
+	now := time.Now()
+	pods := []corev1.Pod{
+		createTestPod("aeron-0", "10.0.0.1", "Running", now.Add(-30*time.Minute)),
+		createTestPod("aeron-0", "10.0.1.1", "Running", now.Add(-20*time.Minute)),
+		createTestPodWithoutIP("aeron-1", "Pending", now.Add(-10*time.Minute)),
+		createTestPod("aeron-9", "10.0.9.1", "Running", now.Add(-40*time.Minute)),
+		createTestPodWithLabel("web-0", "10.0.0.5", "Running", now.Add(-50*time.Minute), "app", "web"),
+	}
+	pods[0].Namespace = "test-namespace"
+	pods[1].Namespace = "other-namespace"
+	pods[2].Namespace = "test-namespace"
+	pods[3].Namespace = "unrelated-namespace"
+	pods[4].Namespace = "test-namespace"
+	return pods
+}
+
+func TestFabricFromUnstructured(t *testing.T) {
+	fabric, err := FabricFromUnstructured(testFabric())
+	if err != nil {
+		t.Fatalf("FabricFromUnstructured() error = %v", err)
+	}
+	if fabric.Name != "fabric" || fabric.Spec.Strategy != StrategyRing || fabric.Spec.DiscoveryPort != 9050 ||
+		fabric.Spec.Output.Type != AssignmentAnnotation || len(fabric.Spec.Namespaces) != 2 {
+		t.Errorf("FabricFromUnstructured() = %+v", fabric)
+	}
+
+	copts := fabricControllerOptions(nil, fabric)
+	if copts.MaxPods != 1 || copts.DiscoveryPort != 9050 || copts.HostnameSuffix != DefaultHostnameSuffix ||
+		len(copts.FabricOwners) != 1 || copts.FabricOwners[0].Kind != FabricKind {
+		t.Errorf("fabricControllerOptions() = %+v", copts)
+	}
+}
+
+func TestReconcileFabric(t *testing.T) {
+	pods := fabricTestPods()
+	var objects []runtime.Object
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+
+	fabric, err := FabricFromUnstructured(testFabric())
+	if err != nil {
+		t.Fatalf("FabricFromUnstructured() error = %v", err)
+	}
+	now := time.Now()
+	status := ReconcileFabric(context.TODO(), clientset, fabric, pods, now)
+
+	if status.Error != "" || status.Members != 2 || status.UnhealthyMembers != 1 || status.ObservedGeneration != 3 {
+		t.Fatalf("ReconcileFabric() = %+v, expected 2 members and 1 unhealthy", status)
+	}
+	if status.Unhealthy[0] != (FabricUnhealthyPod{Name: "aeron-1", Namespace: "test-namespace", Reason: ExplainReasonNoIP, Detail: "has no IP address yet"}) {
+		t.Errorf("Unhealthy = %+v", status.Unhealthy)
+	}
+	if !status.LastReconcileTime.Time.Equal(now) {
+		t.Errorf("LastReconcileTime = %v, expected %v", status.LastReconcileTime, now)
+	}
+
+	// Same named pods in different namespaces are assigned each other
+	pod, err := clientset.CoreV1().Pods("other-namespace").Get(context.TODO(), "aeron-0", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get pod: %v", err)
+	}
+	assigned, err := decodeMembers(pod.Annotations[AssignmentAnnotationKey])
+	if err != nil || len(assigned) != 1 || assigned[0].Key() != "test-namespace/aeron-0" || assigned[0].Endpoint() != "10.0.0.1:9050" {
+		t.Errorf("other-namespace/aeron-0 assigned %+v (%v), expected test-namespace/aeron-0", assigned, err)
+	}
+}
+
+func TestReconcileFabricInvalidSelector(t *testing.T) {
+	fabric, err := FabricFromUnstructured(testFabric())
+	if err != nil {
+		t.Fatalf("FabricFromUnstructured() error = %v", err)
+	}
+	fabric.Spec.Selector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Near"}}}
+
+	status := ReconcileFabric(context.TODO(), fake.NewSimpleClientset(), fabric, fabricTestPods(), time.Now())
+	if status.Error == "" || status.Members != 0 {
+		t.Errorf("ReconcileFabric() = %+v, expected a selector error", status)
+	}
+}
+
+func TestRunFabricController(t *testing.T) {
+	pods := fabricTestPods()
+	var objects []runtime.Object
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{FabricGVR: FabricKind + "List"}, testFabric())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- RunFabricController(ctx, FabricControllerOptions{Clientset: clientset, Dynamic: dynamicClient, PodSelector: DefaultLabelSelector})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		obj, err := dynamicClient.Resource(FabricGVR).Namespace("test-namespace").Get(context.TODO(), "fabric", metav1.GetOptions{})
+		if err == nil {
+			if members, found, _ := unstructured.NestedInt64(obj.Object, "status", "members"); found && members == 2 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Fabric controller never reported 2 members")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("RunFabricController() error = %v", err)
+	}
+}
+
+func TestReconcileOverlappingFabrics(t *testing.T) {
+	pods := fabricTestPods()
+	var objects []runtime.Object
+	for i := range pods {
+		objects = append(objects, &pods[i])
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+
+	older := testFabric()
+	older.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-time.Hour)))
+	newer := testFabric()
+	newer.SetName("fabric-b")
+	newer.SetUID("uid-fabric-b")
+	newer.SetCreationTimestamp(metav1.NewTime(time.Now()))
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{FabricGVR: FabricKind + "List"}, older, newer)
+
+	fopts := FabricControllerOptions{Clientset: clientset, Dynamic: dynamicClient}
+	reconcileFabricObjects(context.TODO(), fopts, []runtime.Object{newer, older}, pods)
+
+	status := func(name string) FabricStatus {
+		obj, err := dynamicClient.Resource(FabricGVR).Namespace("test-namespace").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get fabric %s: %v", name, err)
+		}
+		var fabric Fabric
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &fabric); err != nil {
+			t.Fatalf("Invalid fabric %s: %v", name, err)
+		}
+		return fabric.Status
+	}
+
+	if members := status("fabric").Members; members != 2 {
+		t.Errorf("Older fabric members = %d, expected 2", members)
+	}
+	newerStatus := status("fabric-b")
+	if newerStatus.Members != 0 {
+		t.Errorf("Newer fabric members = %d, expected its pods to stay with the older fabric", newerStatus.Members)
+	}
+	conflicts := 0
+	for _, unhealthy := range newerStatus.Unhealthy {
+		if unhealthy.Reason == SkipReasonSelectedByOtherFabric {
+			conflicts++
+		}
+	}
+	if conflicts != 3 || newerStatus.UnhealthyMembers != 3 {
+		t.Errorf("Newer fabric unhealthy = %+v, expected every pod reported as %s", newerStatus.Unhealthy, SkipReasonSelectedByOtherFabric)
+	}
+}
@@ -0,0 +1,143 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Where the address of a pod using host networking is taken from
+const (
+	HostNetworkAddressPodIP            = "PodIP"
+	HostNetworkAddressInternalIP       = "InternalIP"
+	HostNetworkAddressExternalIP       = "ExternalIP"
+	HostNetworkAddressAnnotationPrefix = "annotation:"
+)
+
+// How pods using host networking are named to the resolver
+const (
+	HostNetworkNamingPod  = "pod"
+	HostNetworkNamingNode = "node"
+)
+
+// HostNetwork configures the handling of media driver pods running with hostNetwork: true
+type HostNetwork struct {
+	// Address is where their address is taken from, one of the HostNetworkAddress constants, or
+	// annotation:<key> for an address annotated onto their Node, empty meaning HostNetworkAddressPodIP
+	Address string
+	// Naming is how they are named to the resolver, one of the HostNetworkNaming constants, empty meaning HostNetworkNamingPod
+	Naming string
+}
+
+// Validate checks the address source and naming scheme are known
+func (h HostNetwork) Validate() error {
+	switch {
+	case h.Address == "", h.Address == HostNetworkAddressPodIP,
+		h.Address == HostNetworkAddressInternalIP, h.Address == HostNetworkAddressExternalIP:
+	case strings.HasPrefix(h.Address, HostNetworkAddressAnnotationPrefix) && h.Address != HostNetworkAddressAnnotationPrefix:
+	default:
+		return fmt.Errorf("invalid host network address %q, expected %s, %s, %s or %s<key>", h.Address,
+			HostNetworkAddressPodIP, HostNetworkAddressInternalIP, HostNetworkAddressExternalIP, HostNetworkAddressAnnotationPrefix)
+	}
+	switch h.Naming {
+	case "", HostNetworkNamingPod, HostNetworkNamingNode:
+	default:
+		return fmt.Errorf("invalid host network naming %q, expected %s or %s", h.Naming, HostNetworkNamingPod, HostNetworkNamingNode)
+	}
+	return nil
+}
+
+// usesNode returns whether the address of a host-network pod is looked up from its Node
+func (h HostNetwork) usesNode() bool {
+	return h.Address != "" && h.Address != HostNetworkAddressPodIP
+}
+
+// nodeCache holds the Nodes already fetched during one discovery, by name
+type nodeCache map[string]*v1.Node
+
+// get returns the named Node, fetching it from the API the first time, or nil if it doesn't exist
+func (c nodeCache) get(ctx context.Context, opts Options, name string) (*v1.Node, error) {
+	if node, ok := c[name]; ok {
+		return node, nil
+	}
+	var node *v1.Node
+	err := callWithRetry(ctx, opts, "get_node", func(ctx context.Context) error {
+		var err error
+		node, err = opts.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		node, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+	c[name] = node
+	return node, nil
+}
+
+// podAddress returns the address a pod's resolver is reached at, and where it was found, one of the IPSource constants
+// Pods using host networking are reached at their Node's address, and others at their Multus network's
+func podAddress(ctx context.Context, opts Options, nodes nodeCache, pod v1.Pod) (string, string, error) {
+	if pod.Spec.HostNetwork {
+		return hostNetworkIP(ctx, opts, nodes, pod)
+	}
+	return SelectIP(pod, PodSecondaryInterface(pod, opts.SecondaryInterface))
+}
+
+// hostNetworkIP returns the address of a pod using host networking, chosen by opts.HostNetwork.Address,
+// or no address if its Node isn't known yet
+func hostNetworkIP(ctx context.Context, opts Options, nodes nodeCache, pod v1.Pod) (string, string, error) {
+	selection, err := ParseIPSelection(opts.SecondaryInterface.IPSelection)
+	if err != nil {
+		return "", "", err
+	}
+	if !opts.HostNetwork.usesNode() {
+		return selection.podIP(pod), IPSourceHostNetwork, nil
+	}
+
+	source := IPSourceNodeAddress
+	if strings.HasPrefix(opts.HostNetwork.Address, HostNetworkAddressAnnotationPrefix) {
+		source = IPSourceNodeAnnotation
+	}
+	if pod.Spec.NodeName == "" {
+		return "", source, nil
+	}
+	node, err := nodes.get(ctx, opts, pod.Spec.NodeName)
+	if err != nil || node == nil {
+		return "", source, err
+	}
+
+	var ips []string
+	if key, ok := strings.CutPrefix(opts.HostNetwork.Address, HostNetworkAddressAnnotationPrefix); ok {
+		ips = strings.Split(node.Annotations[key], ",")
+	} else {
+		for _, address := range node.Status.Addresses {
+			if string(address.Type) == opts.HostNetwork.Address {
+				ips = append(ips, address.Address)
+			}
+		}
+	}
+	ip, _ := selection.Select(ips)
+	return ip, source, nil
+}
@@ -0,0 +1,189 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// createHostNetworkPod returns a media driver pod using host networking on the named node
+func createHostNetworkPod(name, nodeIP, nodeName string, creationTime time.Time) corev1.Pod {
+	pod := createTestPod(name, nodeIP, "Running", creationTime)
+	pod.Namespace = "test-namespace"
+	pod.Spec.HostNetwork = true
+	pod.Spec.NodeName = nodeName
+	return pod
+}
+
+// createTestNode returns a node with an internal and an external address and the annotations given
+func createTestNode(name, internalIP, externalIP string, annotations map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: name},
+				{Type: corev1.NodeInternalIP, Address: internalIP},
+				{Type: corev1.NodeExternalIP, Address: externalIP},
+			},
+		},
+	}
+}
+
+func TestDiscoverHostNetworkPods(t *testing.T) {
+	tests := []struct {
+		name           string
+		hostNetwork    HostNetwork
+		expectedIPs    []string
+		expectedSource string
+		expectedNames  []string
+	}{
+		{
+			name:           "pod IP by default",
+			expectedIPs:    []string{"10.0.0.1", "10.0.0.2"},
+			expectedSource: IPSourceHostNetwork,
+			expectedNames:  []string{"aeron-0.test-namespace.aeron", "aeron-1.test-namespace.aeron"},
+		},
+		{
+			name:           "node internal IP",
+			hostNetwork:    HostNetwork{Address: HostNetworkAddressInternalIP},
+			expectedIPs:    []string{"10.0.0.1", "10.0.0.2"},
+			expectedSource: IPSourceNodeAddress,
+			expectedNames:  []string{"aeron-0.test-namespace.aeron", "aeron-1.test-namespace.aeron"},
+		},
+		{
+			name:           "node external IP named after the node",
+			hostNetwork:    HostNetwork{Address: HostNetworkAddressExternalIP, Naming: HostNetworkNamingNode},
+			expectedIPs:    []string{"203.0.113.1", "203.0.113.2"},
+			expectedSource: IPSourceNodeAddress,
+			expectedNames:  []string{"worker-1.aeron", "worker-2.aeron"},
+		},
+		{
+			name:           "node annotation",
+			hostNetwork:    HostNetwork{Address: HostNetworkAddressAnnotationPrefix + "example.com/fabric-ip"},
+			expectedIPs:    []string{"192.168.10.1", "192.168.10.2"},
+			expectedSource: IPSourceNodeAnnotation,
+			expectedNames:  []string{"aeron-0.test-namespace.aeron", "aeron-1.test-namespace.aeron"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := createHostNetworkPod("aeron-0", "10.0.0.1", "worker-1", time.Now().Add(-10*time.Minute))
+			second := createHostNetworkPod("aeron-1", "10.0.0.2", "worker-2", time.Now().Add(-5*time.Minute))
+			// Multus doesn't attach host-network pods, so network-status is never consulted
+			second.Annotations = map[string]string{NetworkStatusAnnotation: `[{"name":"aeron-network","interface":"net1","ips":["172.16.0.2"]}]`}
+			clientset := fake.NewSimpleClientset(&first, &second,
+				createTestNode("worker-1", "10.0.0.1", "203.0.113.1", map[string]string{"example.com/fabric-ip": "192.168.10.1"}),
+				createTestNode("worker-2", "10.0.0.2", "203.0.113.2", map[string]string{"example.com/fabric-ip": "fd00::2, 192.168.10.2"}))
+
+			opts := testOptions(clientset)
+			opts.HostNetwork = tt.hostNetwork
+			opts.SecondaryInterface.IPSelection = IPSelectionIPv4
+			pods, _, err := DiscoverPods(context.TODO(), opts)
+			if err != nil {
+				t.Fatalf("DiscoverPods() error = %v", err)
+			}
+			if len(pods) != len(tt.expectedIPs) {
+				t.Fatalf("DiscoverPods() returned %d pods, expected %d", len(pods), len(tt.expectedIPs))
+			}
+			for i, pod := range pods {
+				if pod.IP != tt.expectedIPs[i] || pod.IPSource != tt.expectedSource || pod.ResolverName != tt.expectedNames[i] {
+					t.Errorf("Pod %s = (%s, %s, %s), expected (%s, %s, %s)", pod.Name, pod.IP, pod.IPSource, pod.ResolverName,
+						tt.expectedIPs[i], tt.expectedSource, tt.expectedNames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiscoverHostNetworkPodsLooksUpEachNodeOnce(t *testing.T) {
+	// A pod not yet scheduled, and one on a node that has gone, have no address yet
+	first := createHostNetworkPod("aeron-0", "10.0.0.1", "worker-1", time.Now().Add(-10*time.Minute))
+	second := createHostNetworkPod("aeron-1", "10.0.0.1", "worker-1", time.Now().Add(-5*time.Minute))
+	unscheduled := createHostNetworkPod("aeron-2", "", "", time.Now().Add(-2*time.Minute))
+	gone := createHostNetworkPod("aeron-3", "10.0.0.3", "worker-3", time.Now().Add(-1*time.Minute))
+	clientset := fake.NewSimpleClientset(&first, &second, &unscheduled, &gone, createTestNode("worker-1", "10.0.0.1", "203.0.113.1", nil))
+
+	opts := testOptions(clientset)
+	opts.HostNetwork = HostNetwork{Address: HostNetworkAddressInternalIP}
+	pods, _, err := DiscoverPods(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	if len(pods) != 2 || pods[0].Name != "aeron-0" || pods[1].Name != "aeron-1" {
+		t.Errorf("DiscoverPods() = %v, expected aeron-0 and aeron-1", pods)
+	}
+
+	var nodeGets []string
+	for _, action := range clientset.Actions() {
+		if action.GetResource().Resource == "nodes" {
+			nodeGets = append(nodeGets, action.GetVerb())
+		}
+	}
+	if len(nodeGets) != 2 {
+		t.Errorf("Expected one get each for worker-1 and worker-3, got %v", nodeGets)
+	}
+}
+
+func TestRenderHostNetworkPod(t *testing.T) {
+	// Under host networking the hostname is the node's, so PodName comes from POD_NAME
+	self := createHostNetworkPod("aeron-0", "10.0.0.1", "worker-1", time.Now().Add(-10*time.Minute))
+	clientset := fake.NewSimpleClientset(&self, createTestNode("worker-1", "10.0.0.1", "203.0.113.1", nil))
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.HostNetwork = HostNetwork{Address: HostNetworkAddressExternalIP, Naming: HostNetworkNamingNode}
+	result, err := Render(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	expected := RenderProperties([]string{"203.0.113.1:8050"}, 8050, "worker-1.aeron", "203.0.113.1")
+	if result.Properties != expected {
+		t.Errorf("Render() properties = %q, expected %q", result.Properties, expected)
+	}
+	if result.SelfIPSource != IPSourceNodeAddress {
+		t.Errorf("Render() self IP source = %s, expected %s", result.SelfIPSource, IPSourceNodeAddress)
+	}
+}
+
+func TestHostNetworkValidate(t *testing.T) {
+	tests := []struct {
+		hostNetwork HostNetwork
+		valid       bool
+	}{
+		{HostNetwork{}, true},
+		{HostNetwork{Address: HostNetworkAddressPodIP, Naming: HostNetworkNamingPod}, true},
+		{HostNetwork{Address: HostNetworkAddressInternalIP, Naming: HostNetworkNamingNode}, true},
+		{HostNetwork{Address: HostNetworkAddressExternalIP}, true},
+		{HostNetwork{Address: "annotation:example.com/fabric-ip"}, true},
+		{HostNetwork{Address: "annotation:"}, false},
+		{HostNetwork{Address: "Hostname"}, false},
+		{HostNetwork{Naming: "host"}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.hostNetwork.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v Validate() error = %v, expected valid %v", tt.hostNetwork, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Annotations configuring injection into a media driver pod
+const (
+	// InjectAnnotation set to "false" opts a pod out of injection
+	InjectAnnotation = "aeron.io/inject"
+	// InjectImageAnnotation overrides the bootstrap image
+	InjectImageAnnotation = "aeron.io/inject-image"
+	// InjectContainerAnnotation names the media driver container, defaulting to "media-driver" or the first container
+	InjectContainerAnnotation = "aeron.io/inject-container"
+	// InjectConfigDirAnnotation overrides the directory the bootstrap properties are written to
+	InjectConfigDirAnnotation = "aeron.io/inject-config-dir"
+	// InjectShmSizeAnnotation limits the size of the injected /dev/shm volume
+	InjectShmSizeAnnotation = "aeron.io/inject-shm-size"
+	// InjectArgsAnnotation set to "false" leaves the media driver's arguments unchanged, for wrapper entrypoints
+	// that read AERON_MD_BOOTSTRAP_PATH instead of passing their arguments to the media driver
+	InjectArgsAnnotation = "aeron.io/inject-args"
+	// InjectedAnnotation is set on pods that have been injected
+	InjectedAnnotation = "aeron.io/injected"
+)
+
+// Names of what is injected
+const (
+	InjectedInitContainerName  = "aeron-k8s-bootstrap"
+	InjectedConfigVolumeName   = "aeron-md-config-dir"
+	InjectedShmVolumeName      = "dshm"
+	DefaultMediaDriverName     = "media-driver"
+	DefaultInjectConfigDir     = "/etc/aeron"
+	mediaDriverLabelKey        = "aeron.io/media-driver"
+	shmMountPath               = "/dev/shm"
+	bootstrapPropertiesFile    = "bootstrap.properties"
+	maxAdmissionReviewBodySize = 3 * 1024 * 1024
+)
+
+// InjectionConfig configures what the webhook injects into media driver pods
+type InjectionConfig struct {
+	// Image is the bootstrap image of the injected init container
+	Image string
+	// ImagePullPolicy of the injected init container, empty for the cluster default
+	ImagePullPolicy v1.PullPolicy
+	// Env is passed to the injected init container, e.g. AERON_MD_DISCOVERY_PORT
+	Env []v1.EnvVar
+}
+
+// InjectPod injects the bootstrap init container, its config volume, a /dev/shm volume and the bootstrap
+// properties path into a media driver pod, returning false if the pod is not to be injected
+// The path is passed to the media driver as its first argument, unless opted out with InjectArgsAnnotation, and as AERON_MD_BOOTSTRAP_PATH
+func InjectPod(pod *v1.Pod, config InjectionConfig) (bool, error) {
+	if pod.Labels[mediaDriverLabelKey] != "true" || pod.Annotations[InjectAnnotation] == "false" || pod.Annotations[InjectedAnnotation] == "true" {
+		return false, nil
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == InjectedInitContainerName {
+			return false, nil
+		}
+	}
+
+	driver, err := mediaDriverContainer(pod)
+	if err != nil {
+		return false, err
+	}
+	configDir := DefaultInjectConfigDir
+	if dir := pod.Annotations[InjectConfigDirAnnotation]; dir != "" {
+		configDir = path.Clean(dir)
+	}
+	image := config.Image
+	if override := pod.Annotations[InjectImageAnnotation]; override != "" {
+		image = override
+	}
+	if image == "" {
+		return false, fmt.Errorf("no bootstrap image configured")
+	}
+	var shmSize *resource.Quantity
+	if size := pod.Annotations[InjectShmSizeAnnotation]; size != "" {
+		quantity, err := resource.ParseQuantity(size)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s annotation %q: %v", InjectShmSizeAnnotation, size, err)
+		}
+		shmSize = &quantity
+	}
+
+	propertiesPath := path.Join(configDir, bootstrapPropertiesFile)
+	configMount := v1.VolumeMount{Name: InjectedConfigVolumeName, MountPath: configDir}
+
+	// POD_NAME identifies the pod even under host networking, where the hostname is the node's
+	env := append([]v1.EnvVar{
+		{Name: "AERON_MD_BOOTSTRAP_PATH", Value: propertiesPath},
+		{Name: "POD_NAME", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+	}, config.Env...)
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, v1.Container{
+		Name:            InjectedInitContainerName,
+		Image:           image,
+		ImagePullPolicy: config.ImagePullPolicy,
+		Env:             env,
+		VolumeMounts:    []v1.VolumeMount{configMount},
+	})
+
+	if !hasVolume(pod, InjectedConfigVolumeName) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+			Name:         InjectedConfigVolumeName,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+	}
+	if !hasMountAt(driver, configDir) {
+		driver.VolumeMounts = append(driver.VolumeMounts, configMount)
+	}
+
+	// Aeron needs a memory backed /dev/shm, larger than the container runtime's default
+	if !hasMountAt(driver, shmMountPath) {
+		if !hasVolume(pod, InjectedShmVolumeName) {
+			pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+				Name:         InjectedShmVolumeName,
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory, SizeLimit: shmSize}},
+			})
+		}
+		driver.VolumeMounts = append(driver.VolumeMounts, v1.VolumeMount{Name: InjectedShmVolumeName, MountPath: shmMountPath})
+	}
+
+	if !hasEnv(driver, "AERON_MD_BOOTSTRAP_PATH") {
+		driver.Env = append(driver.Env, v1.EnvVar{Name: "AERON_MD_BOOTSTRAP_PATH", Value: propertiesPath})
+	}
+	// The bootstrap properties come first, so the pod's own properties files override them
+	if pod.Annotations[InjectArgsAnnotation] != "false" && !slices.Contains(driver.Args, propertiesPath) {
+		driver.Args = append([]string{propertiesPath}, driver.Args...)
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[InjectedAnnotation] = "true"
+	return true, nil
+}
+
+// mediaDriverContainer returns the container running the media driver
+func mediaDriverContainer(pod *v1.Pod) (*v1.Container, error) {
+	name := pod.Annotations[InjectContainerAnnotation]
+	if name == "" {
+		name = DefaultMediaDriverName
+	}
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i], nil
+		}
+	}
+	if pod.Annotations[InjectContainerAnnotation] == "" && len(pod.Spec.Containers) > 0 {
+		return &pod.Spec.Containers[0], nil
+	}
+	return nil, fmt.Errorf("media driver container %q not found", name)
+}
+
+// hasVolume returns whether a pod has a volume with the given name
+func hasVolume(pod *v1.Pod, name string) bool {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEnv returns whether a container already sets the named environment variable
+func hasEnv(container *v1.Container, name string) bool {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMountAt returns whether a container already mounts something at mountPath
+func hasMountAt(container *v1.Container, mountPath string) bool {
+	for _, mount := range container.VolumeMounts {
+		if path.Clean(mount.MountPath) == mountPath {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonPatchOperation is one RFC 6902 JSON patch operation
+type jsonPatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// injectionPatch builds the JSON patch turning original into injected, replacing each changed field whole
+func injectionPatch(original, injected *v1.Pod) []jsonPatchOperation {
+	var patch []jsonPatchOperation
+	add := func(path string, value any) {
+		patch = append(patch, jsonPatchOperation{Op: "add", Path: path, Value: value})
+	}
+
+	if original.Annotations == nil {
+		add("/metadata/annotations", injected.Annotations)
+	} else {
+		add("/metadata/annotations/"+escapeJSONPointer(InjectedAnnotation), injected.Annotations[InjectedAnnotation])
+	}
+	add("/spec/initContainers", injected.Spec.InitContainers)
+	add("/spec/volumes", injected.Spec.Volumes)
+	for i := range injected.Spec.Containers {
+		originalJSON, _ := json.Marshal(original.Spec.Containers[i])
+		injectedJSON, _ := json.Marshal(injected.Spec.Containers[i])
+		if string(originalJSON) != string(injectedJSON) {
+			patch = append(patch, jsonPatchOperation{Op: "replace", Path: fmt.Sprintf("/spec/containers/%d", i), Value: injected.Spec.Containers[i]})
+		}
+	}
+	return patch
+}
+
+// escapeJSONPointer escapes a key for use in a JSON pointer
+func escapeJSONPointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// Admit answers an AdmissionRequest for a pod, patching in the bootstrap if it is a media driver pod
+// Pods are always admitted, a pod that can't be injected is admitted unchanged with a warning
+func Admit(request *admissionv1.AdmissionRequest, config InjectionConfig) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+
+	var pod v1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		response.Warnings = []string{fmt.Sprintf("aeron-k8s-bootstrap: failed to decode pod: %v", err)}
+		return response
+	}
+	// Pods created by a controller have no name yet
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName
+	}
+
+	injected := pod.DeepCopy()
+	ok, err := InjectPod(injected, config)
+	if err != nil {
+		slog.Warn("Failed to inject bootstrap", LogKeyPod, name, LogKeyNamespace, request.Namespace, "error", err)
+		response.Warnings = []string{fmt.Sprintf("aeron-k8s-bootstrap: not injected: %v", err)}
+		return response
+	}
+	if !ok {
+		return response
+	}
+
+	patch, err := json.Marshal(injectionPatch(&pod, injected))
+	if err != nil {
+		response.Warnings = []string{fmt.Sprintf("aeron-k8s-bootstrap: failed to encode patch: %v", err)}
+		return response
+	}
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = patch
+	response.PatchType = &patchType
+	slog.Info("Injected bootstrap", LogKeyPod, name, LogKeyNamespace, request.Namespace)
+	return response
+}
+
+// InjectionHandler serves the mutating admission webhook injecting the bootstrap into media driver pods
+func InjectionHandler(config InjectionConfig) http.Handler {
+	return admissionHandler(func(_ context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		return Admit(request, config)
+	})
+}
+
+// admissionHandler serves AdmissionReviews, answering each request with admit
+func admissionHandler(admit func(context.Context, *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAdmissionReviewBodySize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+			return
+		}
+		var review admissionv1.AdmissionReview
+		if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, "invalid AdmissionReview", http.StatusBadRequest)
+			return
+		}
+
+		review.Response = admit(r.Context(), review.Request)
+		review.Request = nil
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(review); err != nil {
+			slog.Warn("Failed to write AdmissionReview response", "error", err)
+		}
+	})
+}
@@ -0,0 +1,313 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reviewFixture posts an AdmissionReview fixture to the injection webhook, returning the request and response
+func reviewFixture(t *testing.T, name string, config InjectionConfig) (*admissionv1.AdmissionRequest, *admissionv1.AdmissionResponse) {
+	t.Helper()
+	request := readReviewFixture(t, name)
+	response := reviewFixtureWith(t, InjectionHandler(config), name)
+	if !response.Allowed {
+		t.Error("Expected the pod to be allowed")
+	}
+	return request.Request, response
+}
+
+// readReviewFixture reads an AdmissionReview fixture
+func readReviewFixture(t *testing.T, name string) admissionv1.AdmissionReview {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "admission", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		t.Fatalf("Failed to decode fixture: %v", err)
+	}
+	return review
+}
+
+// reviewFixtureWith posts an AdmissionReview fixture to a webhook handler, returning its response
+func reviewFixtureWith(t *testing.T, handler http.Handler, name string) *admissionv1.AdmissionResponse {
+	t.Helper()
+	request := readReviewFixture(t, name)
+	body, _ := json.Marshal(request)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Webhook returned %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(recorder.Body.Bytes(), &review); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if review.Response == nil {
+		t.Fatal("AdmissionReview has no response")
+	}
+	if review.Response.UID != request.Request.UID {
+		t.Errorf("Response UID = %q, expected %q", review.Response.UID, request.Request.UID)
+	}
+	return review.Response
+}
+
+// patchedPod applies the response patch to the requested pod
+func patchedPod(t *testing.T, request *admissionv1.AdmissionRequest, response *admissionv1.AdmissionResponse) corev1.Pod {
+	t.Helper()
+	if response.PatchType == nil || *response.PatchType != admissionv1.PatchTypeJSONPatch {
+		t.Fatalf("PatchType = %v, expected JSONPatch", response.PatchType)
+	}
+	patch, err := jsonpatch.DecodePatch(response.Patch)
+	if err != nil {
+		t.Fatalf("Failed to decode patch: %v", err)
+	}
+	patched, err := patch.Apply(request.Object.Raw)
+	if err != nil {
+		t.Fatalf("Failed to apply patch: %v", err)
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(patched, &pod); err != nil {
+		t.Fatalf("Failed to decode patched pod: %v", err)
+	}
+	return pod
+}
+
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+func findVolume(volumes []corev1.Volume, name string) *corev1.Volume {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+func mountPath(container *corev1.Container, volume string) string {
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == volume {
+			return mount.MountPath
+		}
+	}
+	return ""
+}
+
+var testInjectionConfig = InjectionConfig{
+	Image:           "ghcr.io/james-masson/aeron-k8s-bootstrap/aeron-k8s-bootstrap:latest",
+	ImagePullPolicy: corev1.PullIfNotPresent,
+	Env:             []corev1.EnvVar{{Name: "AERON_MD_DISCOVERY_PORT", Value: "8050"}},
+}
+
+func TestInjectMediaDriver(t *testing.T) {
+	request, response := reviewFixture(t, "media-driver.json", testInjectionConfig)
+	pod := patchedPod(t, request, response)
+
+	init := findContainer(pod.Spec.InitContainers, InjectedInitContainerName)
+	if init == nil {
+		t.Fatal("Init container not injected")
+	}
+	if init.Image != testInjectionConfig.Image || init.ImagePullPolicy != corev1.PullIfNotPresent {
+		t.Errorf("Init container image = %s (%s)", init.Image, init.ImagePullPolicy)
+	}
+	expectedEnv := []corev1.EnvVar{
+		{Name: "AERON_MD_BOOTSTRAP_PATH", Value: "/etc/aeron/bootstrap.properties"},
+		{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+		{Name: "AERON_MD_DISCOVERY_PORT", Value: "8050"},
+	}
+	if !reflect.DeepEqual(init.Env, expectedEnv) {
+		t.Errorf("Init container env = %v, expected %v", init.Env, expectedEnv)
+	}
+	if got := mountPath(init, InjectedConfigVolumeName); got != "/etc/aeron" {
+		t.Errorf("Init container config mount = %q, expected /etc/aeron", got)
+	}
+
+	driver := findContainer(pod.Spec.Containers, "media-driver")
+	expectedArgs := []string{"/etc/aeron/bootstrap.properties", "/etc/aeron/aeron.properties"}
+	if !slices.Equal(driver.Args, expectedArgs) {
+		t.Errorf("Driver args = %v, expected %v", driver.Args, expectedArgs)
+	}
+	expectedDriverEnv := []corev1.EnvVar{{Name: "AERON_MD_BOOTSTRAP_PATH", Value: "/etc/aeron/bootstrap.properties"}}
+	if !reflect.DeepEqual(driver.Env, expectedDriverEnv) {
+		t.Errorf("Driver env = %v, expected %v", driver.Env, expectedDriverEnv)
+	}
+	if got := mountPath(driver, InjectedConfigVolumeName); got != "/etc/aeron" {
+		t.Errorf("Driver config mount = %q, expected /etc/aeron", got)
+	}
+	if got := mountPath(driver, InjectedShmVolumeName); got != "/dev/shm" {
+		t.Errorf("Driver shm mount = %q, expected /dev/shm", got)
+	}
+
+	if volume := findVolume(pod.Spec.Volumes, InjectedConfigVolumeName); volume == nil || volume.EmptyDir == nil {
+		t.Errorf("Config volume = %+v, expected an emptyDir", volume)
+	}
+	shm := findVolume(pod.Spec.Volumes, InjectedShmVolumeName)
+	if shm == nil || shm.EmptyDir == nil || shm.EmptyDir.Medium != corev1.StorageMediumMemory || shm.EmptyDir.SizeLimit != nil {
+		t.Errorf("Shm volume = %+v, expected an unlimited Memory emptyDir", shm)
+	}
+
+	if pod.Annotations[InjectedAnnotation] != "true" {
+		t.Errorf("Expected the %s annotation, got %v", InjectedAnnotation, pod.Annotations)
+	}
+	if pod.Spec.ServiceAccountName != "aeron-k8s-bootstrap" || pod.Labels["app"] != "aeron" {
+		t.Error("Patch changed fields it should not have")
+	}
+}
+
+func TestInjectAnnotationOverrides(t *testing.T) {
+	request, response := reviewFixture(t, "annotated.json", testInjectionConfig)
+	pod := patchedPod(t, request, response)
+
+	init := findContainer(pod.Spec.InitContainers, InjectedInitContainerName)
+	if init == nil || init.Image != "registry.example.com/aeron-k8s-bootstrap:1.2.3" {
+		t.Fatalf("Init container = %+v, expected the annotated image", init)
+	}
+	if init.Env[0].Value != "/opt/aeron/conf/bootstrap.properties" {
+		t.Errorf("Bootstrap path = %q, expected the annotated config dir", init.Env[0].Value)
+	}
+
+	sidecar := findContainer(pod.Spec.Containers, "sidecar")
+	if len(sidecar.Args) != 0 || len(sidecar.VolumeMounts) != 0 {
+		t.Errorf("Sidecar was modified: %+v", sidecar)
+	}
+	driver := findContainer(pod.Spec.Containers, "driver")
+	if len(driver.Args) != 0 {
+		t.Errorf("Driver args = %v, expected none with %s: \"false\"", driver.Args, InjectArgsAnnotation)
+	}
+	if !reflect.DeepEqual(driver.Env, []corev1.EnvVar{{Name: "AERON_MD_BOOTSTRAP_PATH", Value: "/opt/aeron/conf/bootstrap.properties"}}) {
+		t.Errorf("Driver env = %v, expected AERON_MD_BOOTSTRAP_PATH for its wrapper entrypoint", driver.Env)
+	}
+	if got := mountPath(driver, InjectedConfigVolumeName); got != "/opt/aeron/conf" {
+		t.Errorf("Driver config mount = %q, expected /opt/aeron/conf", got)
+	}
+
+	if findVolume(pod.Spec.Volumes, "aeron-properties") == nil {
+		t.Error("Existing volume was removed")
+	}
+	shm := findVolume(pod.Spec.Volumes, InjectedShmVolumeName)
+	if shm == nil || shm.EmptyDir.SizeLimit == nil || shm.EmptyDir.SizeLimit.String() != "1Gi" {
+		t.Errorf("Shm volume = %+v, expected a 1Gi limit", shm)
+	}
+	if len(pod.Annotations) != 6 {
+		t.Errorf("Annotations = %v, expected the originals plus %s", pod.Annotations, InjectedAnnotation)
+	}
+}
+
+func TestInjectKeepsExistingShm(t *testing.T) {
+	request, response := reviewFixture(t, "existing-shm.json", testInjectionConfig)
+	pod := patchedPod(t, request, response)
+
+	driver := findContainer(pod.Spec.Containers, "media-driver")
+	if !slices.Equal(driver.Args, []string{"/etc/aeron/bootstrap.properties"}) {
+		t.Errorf("Driver args = %v, expected the existing argument alone", driver.Args)
+	}
+	if findVolume(pod.Spec.Volumes, InjectedShmVolumeName) != nil {
+		t.Error("Shm volume injected over the pod's own")
+	}
+	if got := mountPath(driver, "shm"); got != "/dev/shm" {
+		t.Errorf("Driver shm mount = %q, expected the pod's own", got)
+	}
+	if len(pod.Spec.Volumes) != 2 {
+		t.Errorf("Volumes = %v, expected shm and %s", pod.Spec.Volumes, InjectedConfigVolumeName)
+	}
+}
+
+func TestInjectSkipped(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		warnings bool
+	}{
+		{"unlabeled.json", false},
+		{"opted-out.json", false},
+		{"missing-container.json", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			_, response := reviewFixture(t, tt.fixture, testInjectionConfig)
+			if response.Patch != nil || response.PatchType != nil {
+				t.Errorf("Expected no patch, got %s", response.Patch)
+			}
+			if got := len(response.Warnings) > 0; got != tt.warnings {
+				t.Errorf("Warnings = %v, expected warnings: %v", response.Warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestInjectPodIdempotent(t *testing.T) {
+	var review admissionv1.AdmissionReview
+	body, _ := os.ReadFile(filepath.Join("testdata", "admission", "media-driver.json"))
+	if err := json.Unmarshal(body, &review); err != nil {
+		t.Fatal(err)
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(review.Request.Object.Raw, &pod); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := InjectPod(&pod, testInjectionConfig); !ok || err != nil {
+		t.Fatalf("First InjectPod() = (%v, %v), expected (true, nil)", ok, err)
+	}
+	if ok, err := InjectPod(&pod, testInjectionConfig); ok || err != nil {
+		t.Errorf("Second InjectPod() = (%v, %v), expected (false, nil)", ok, err)
+	}
+	if len(pod.Spec.InitContainers) != 1 {
+		t.Errorf("Init containers = %d, expected 1", len(pod.Spec.InitContainers))
+	}
+}
+
+func TestInjectionHandlerRejectsBadRequests(t *testing.T) {
+	handler := InjectionHandler(testInjectionConfig)
+	tests := []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "not json", http.StatusBadRequest},
+		{http.MethodPost, `{"kind": "AdmissionReview"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, "/mutate", bytes.NewBufferString(tt.body)))
+		if recorder.Code != tt.code {
+			t.Errorf("%s %q returned %d, expected %d", tt.method, tt.body, recorder.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// IP selection rules, choosing one of the addresses of a network carrying several
+const (
+	IPSelectionFirst       = "first"
+	IPSelectionIPv4        = "ipv4"
+	IPSelectionIPv6        = "ipv6"
+	IPSelectionCIDRPrefix  = "cidr:"
+	IPSelectionIndexPrefix = "index:"
+)
+
+// IPSelection chooses one of the addresses of a network, parsed from a rule by ParseIPSelection
+type IPSelection struct {
+	rule    string
+	ipv4    bool
+	ipv6    bool
+	cidr    *net.IPNet
+	byIndex bool
+	index   int
+}
+
+// ParseIPSelection parses an IP selection rule:
+// - "first", or empty: the first address
+// - "ipv4" or "ipv6": the first address of that family
+// - "cidr:<cidr>": the first address within the CIDR, e.g. "cidr:192.168.0.0/16"
+// - "index:<n>": the address at index n, counting from 0
+// Rules and their prefixes are matched case-insensitively
+func ParseIPSelection(rule string) (IPSelection, error) {
+	rule = strings.TrimSpace(rule)
+	selection := IPSelection{rule: rule}
+	switch {
+	case rule == "" || strings.EqualFold(rule, IPSelectionFirst):
+		selection.rule = IPSelectionFirst
+	case strings.EqualFold(rule, IPSelectionIPv4):
+		selection.ipv4 = true
+	case strings.EqualFold(rule, IPSelectionIPv6):
+		selection.ipv6 = true
+	case hasPrefixFold(rule, IPSelectionCIDRPrefix):
+		_, cidr, err := net.ParseCIDR(rule[len(IPSelectionCIDRPrefix):])
+		if err != nil {
+			return IPSelection{}, fmt.Errorf("invalid IP selection %q: %v", rule, err)
+		}
+		selection.cidr = cidr
+	case hasPrefixFold(rule, IPSelectionIndexPrefix):
+		index, err := strconv.Atoi(rule[len(IPSelectionIndexPrefix):])
+		if err != nil || index < 0 {
+			return IPSelection{}, fmt.Errorf("invalid IP selection %q: index must be a number from 0", rule)
+		}
+		selection.byIndex = true
+		selection.index = index
+	default:
+		return IPSelection{}, fmt.Errorf("invalid IP selection %q, expected %s, %s, %s, %s<cidr> or %s<n>",
+			rule, IPSelectionFirst, IPSelectionIPv4, IPSelectionIPv6, IPSelectionCIDRPrefix, IPSelectionIndexPrefix)
+	}
+	return selection, nil
+}
+
+// hasPrefixFold returns whether s begins with prefix, ignoring case
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// String returns the rule the selection was parsed from
+func (s IPSelection) String() string {
+	if s.rule == "" {
+		return IPSelectionFirst
+	}
+	return s.rule
+}
+
+// Select returns the address chosen from ips, or false if none matches
+// Addresses may carry a prefix length, which is dropped, and invalid addresses are ignored
+func (s IPSelection) Select(ips []string) (string, bool) {
+	var valid []net.IP
+	for _, address := range ips {
+		address = strings.TrimSpace(address)
+		ip := net.ParseIP(address)
+		if ip == nil {
+			if cidrIP, _, err := net.ParseCIDR(address); err == nil {
+				ip = cidrIP
+			}
+		}
+		if ip != nil {
+			valid = append(valid, ip)
+		}
+	}
+
+	if s.byIndex {
+		if s.index < len(valid) {
+			return valid[s.index].String(), true
+		}
+		return "", false
+	}
+	for _, ip := range valid {
+		isIPv4 := ip.To4() != nil
+		switch {
+		case s.ipv4 && !isIPv4, s.ipv6 && isIPv4, s.cidr != nil && !s.cidr.Contains(ip):
+			continue
+		}
+		return ip.String(), true
+	}
+	return "", false
+}
+
+// podIP returns the pod's primary address chosen from status.PodIPs, or status.PodIP if none matches,
+// as a rule meant for a secondary network, e.g. a cidr: one, rarely matches the primary addresses
+func (s IPSelection) podIP(pod v1.Pod) string {
+	var ips []string
+	for _, podIP := range pod.Status.PodIPs {
+		ips = append(ips, podIP.IP)
+	}
+	if ip, ok := s.Select(ips); ok {
+		return ip
+	}
+	return pod.Status.PodIP
+}
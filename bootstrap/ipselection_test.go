@@ -0,0 +1,201 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestIPSelection(t *testing.T) {
+	ips := []string{"not-an-ip", "10.0.0.1", "fd00::1/64", "192.168.1.10/24", "fd00:0:0::2"}
+	tests := []struct {
+		rule     string
+		ips      []string
+		expected string
+		found    bool
+	}{
+		{rule: "", ips: ips, expected: "10.0.0.1", found: true},
+		{rule: "first", ips: ips, expected: "10.0.0.1", found: true},
+		{rule: "IPv4", ips: ips, expected: "10.0.0.1", found: true},
+		{rule: "ipv6", ips: ips, expected: "fd00::1", found: true},
+		{rule: "cidr:192.168.0.0/16", ips: ips, expected: "192.168.1.10", found: true},
+		{rule: "cidr:fd00::/8", ips: ips, expected: "fd00::1", found: true},
+		{rule: "cidr:172.16.0.0/12", ips: ips, found: false},
+		{rule: "CIDR:192.168.0.0/16", ips: ips, expected: "192.168.1.10", found: true},
+		{rule: "index:0", ips: ips, expected: "10.0.0.1", found: true},
+		{rule: "Index:1", ips: ips, expected: "fd00::1", found: true},
+		{rule: "index:3", ips: ips, expected: "fd00::2", found: true},
+		{rule: "index:4", ips: ips, found: false},
+		{rule: "ipv6", ips: []string{"10.0.0.1"}, found: false},
+		{rule: "first", ips: nil, found: false},
+		{rule: "first", ips: []string{"", " "}, found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			selection, err := ParseIPSelection(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseIPSelection(%q) error = %v", tt.rule, err)
+			}
+			ip, found := selection.Select(tt.ips)
+			if ip != tt.expected || found != tt.found {
+				t.Errorf("Select(%v) = (%q, %v), expected (%q, %v)", tt.ips, ip, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+func TestParseIPSelectionInvalid(t *testing.T) {
+	for _, rule := range []string{"last", "cidr:", "cidr:10.0.0.1", "index:", "index:-1", "index:one"} {
+		if _, err := ParseIPSelection(rule); err == nil {
+			t.Errorf("ParseIPSelection(%q) expected an error", rule)
+		}
+	}
+}
+
+func TestSelectIPMultipleAddresses(t *testing.T) {
+	pod := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now())
+	pod.Status.PodIPs = []corev1.PodIP{{IP: "10.0.0.1"}, {IP: "fd00::1"}}
+	pod.Annotations = map[string]string{
+		NetworkStatusAnnotation: `[{"name":"aeron","interface":"net1","ips":["192.168.1.10","fd01::10"]},` +
+			`{"name":"empty","interface":"net2","ips":[]},{"name":"aeron-v6","interface":"net3","ips":["fd02::10"]}]`,
+	}
+
+	tests := []struct {
+		name      string
+		secondary SecondaryInterface
+		ip        string
+		source    string
+	}{
+		{"first by default", SecondaryInterface{}, "192.168.1.10", IPSourceDefaultInterface},
+		{"family", SecondaryInterface{IPSelection: "ipv6"}, "fd01::10", IPSourceDefaultInterface},
+		{"index", SecondaryInterface{IPSelection: "index:1"}, "fd01::10", IPSourceDefaultInterface},
+		{"network without a matching address is skipped", SecondaryInterface{InterfaceName: "net3", IPSelection: "ipv4"}, "192.168.1.10", IPSourceDefaultInterface},
+		{"network without addresses is skipped", SecondaryInterface{InterfaceName: "net2"}, "192.168.1.10", IPSourceDefaultInterface},
+		{"falls back to the pod IP of the family", SecondaryInterface{IPSelection: "cidr:fd00::/16"}, "fd00::1", IPSourcePodIPFallback},
+		{"falls back to status.PodIP without a matching address", SecondaryInterface{IPSelection: "cidr:172.16.0.0/12"}, "10.0.0.1", IPSourcePodIPFallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip, source, err := SelectIP(pod, tt.secondary)
+			if err != nil {
+				t.Fatalf("SelectIP() error = %v", err)
+			}
+			if ip != tt.ip || source != tt.source {
+				t.Errorf("SelectIP() = (%s, %s), expected (%s, %s)", ip, source, tt.ip, tt.source)
+			}
+		})
+	}
+
+	if _, _, err := SelectIP(pod, SecondaryInterface{IPSelection: "last"}); err == nil {
+		t.Error("SelectIP() with an invalid IP selection expected an error")
+	}
+}
+
+func TestSelectIPWithoutMultus(t *testing.T) {
+	pod := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now())
+	pod.Status.PodIPs = []corev1.PodIP{{IP: "10.0.0.1"}, {IP: "fd00::1"}}
+
+	tests := []struct {
+		rule string
+		ip   string
+	}{
+		{"ipv6", "fd00::1"},
+		{"index:1", "fd00::1"},
+		{"cidr:192.168.0.0/16", "10.0.0.1"},
+		{"index:5", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			ip, source, err := SelectIP(pod, SecondaryInterface{IPSelection: tt.rule})
+			if err != nil {
+				t.Fatalf("SelectIP() error = %v", err)
+			}
+			if ip != tt.ip || source != IPSourcePodIP {
+				t.Errorf("SelectIP() = (%s, %s), expected (%s, %s)", ip, source, tt.ip, IPSourcePodIP)
+			}
+		})
+	}
+}
+
+func TestPodInfoEndpoint(t *testing.T) {
+	tests := []struct {
+		ip       string
+		expected string
+	}{
+		{"10.0.0.1", "10.0.0.1:8050"},
+		{"fd00::1", "[fd00::1]:8050"},
+	}
+	for _, tt := range tests {
+		if endpoint := (PodInfo{IP: tt.ip, Port: 8050}).Endpoint(); endpoint != tt.expected {
+			t.Errorf("Endpoint() = %s, expected %s", endpoint, tt.expected)
+		}
+	}
+}
+
+// FuzzParseNetworkStatus checks no network-status annotation makes parsing or address selection panic
+func FuzzParseNetworkStatus(f *testing.F) {
+	f.Add(`[{"name":"aeron","interface":"net1","ips":["192.168.1.10"]}]`)
+	f.Add(`[{"name":"aeron","interface":"net1","ips":[]}]`)
+	f.Add(`[{"name":"aeron","interface":"net1"}]`)
+	f.Add(`[{"name":"aeron","interface":"net1","ips":["fd00::1/64","garbage"]}]`)
+	f.Add(`[{}]`)
+	f.Add(`[null]`)
+	f.Add(`null`)
+	f.Add(``)
+	f.Add(`{`)
+
+	f.Fuzz(func(t *testing.T, annotation string) {
+		networks, err := ParseNetworkStatus(annotation)
+		if err != nil {
+			return
+		}
+		selection, _ := ParseIPSelection(IPSelectionIPv6)
+		for _, network := range networks {
+			selection.Select(network.IPs)
+		}
+	})
+}
+
+// FuzzSelectIP checks no combination of annotations and IP selection rule makes SelectIP panic
+func FuzzSelectIP(f *testing.F) {
+	f.Add(`[{"name":"aeron","interface":"net1","ips":["192.168.1.10"]}]`, "aeron", "", "first", "10.0.0.1")
+	f.Add(`[{"name":"aeron","interface":"net1","ips":[]}]`, "aeron", "net1", "ipv4", "")
+	f.Add(`[{"name":"aeron","interface":"net1"}]`, "", "net1", "index:3", "10.0.0.1")
+	f.Add(`[{"name":"ns/aeron","interface":"aeron0","ips":["fd00::1"]}]`, "ns/aeron@aeron0", "", "cidr:fd00::/8", "fd00::2")
+	f.Add(`[{"name":1}]`, `[{"name":"aeron","interface":"x"}]`, "aeron0", "cidr:bad", "not-an-ip")
+
+	f.Fuzz(func(t *testing.T, networkStatus, networks, iface, rule, podIP string) {
+		pod := corev1.Pod{Status: corev1.PodStatus{PodIP: podIP}}
+		pod.Annotations = map[string]string{NetworkStatusAnnotation: networkStatus, NetworksAnnotation: networks}
+		for _, secondary := range []SecondaryInterface{
+			{IPSelection: rule},
+			{NetworkName: networks, IPSelection: rule},
+			{InterfaceName: iface, IPSelection: rule},
+		} {
+			ip, _, err := SelectIP(pod, secondary)
+			if err == nil && ip != "" && net.ParseIP(ip) == nil {
+				t.Errorf("SelectIP() = %q, which is not an address", ip)
+			}
+		}
+		CheckMultusNetworkStatus(pod)
+	})
+}
@@ -0,0 +1,125 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Formats of the lookup file mapping every media driver's resolver name to its address
+const (
+	// LookupHosts writes "<ip> <name>" lines, in /etc/hosts format
+	LookupHosts = "hosts"
+	// LookupProperties writes "<name>=<ip>:<port>" lines, giving each driver's resolver endpoint
+	LookupProperties = "properties"
+)
+
+// lookupFileBase names the lookup file written beside the bootstrap file, and its ConfigMap or Secret key, before its format extension
+const lookupFileBase = "aeron-names"
+
+// ValidateLookup checks a lookup format is known, empty meaning no lookup file
+func ValidateLookup(format string) error {
+	switch format {
+	case "", LookupHosts, LookupProperties:
+		return nil
+	}
+	return fmt.Errorf("invalid lookup format %q, expected %s or %s", format, LookupHosts, LookupProperties)
+}
+
+// lookupPath returns where the lookup file is written, opts.LookupPath or aeron-names.<format> beside the bootstrap file
+func lookupPath(opts Options) string {
+	if opts.LookupPath != "" {
+		return opts.LookupPath
+	}
+	return filepath.Join(filepath.Dir(opts.BootstrapPath), lookupFileBase+"."+opts.Lookup)
+}
+
+// LookupKey returns the ConfigMap or Secret key holding the lookup in the given format
+func LookupKey(format string) string {
+	return lookupFileBase + "." + format
+}
+
+// RenderLookup renders the lookup file content in the given format for pods, sorted by resolver name
+// Pods without a resolver name or address are left out, and of several pods with the same name the first is used
+func RenderLookup(format string, pods []PodInfo) string {
+	seen := make(map[string]bool, len(pods))
+	var entries []PodInfo
+	for _, pod := range pods {
+		if pod.ResolverName == "" || pod.IP == "" || seen[pod.ResolverName] {
+			continue
+		}
+		// A hosts file can only map names to addresses, not to a predicted member's DNS name
+		if format == LookupHosts && net.ParseIP(pod.IP) == nil {
+			continue
+		}
+		seen[pod.ResolverName] = true
+		entries = append(entries, pod)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ResolverName < entries[j].ResolverName
+	})
+
+	var content strings.Builder
+	for _, pod := range entries {
+		if format == LookupHosts {
+			fmt.Fprintf(&content, "%s %s\n", pod.IP, pod.ResolverName)
+		} else {
+			fmt.Fprintf(&content, "%s=%s\n", pod.ResolverName, pod.Endpoint())
+		}
+	}
+	return content.String()
+}
+
+// writeLookupFile replaces the lookup file at path with content, returning false if it was already up to date
+// It is renamed into place, so clients never read a partially written file
+func writeLookupFile(path, content string) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == content {
+		slog.Debug("Lookup file unchanged", "path", path)
+		return false, nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory %s: %v", dir, err)
+	}
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create lookup file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write lookup file %s: %v", path, err)
+	}
+	slog.Info("Created lookup file", "path", path, "entries", strings.Count(content, "\n"))
+	return true, nil
+}
@@ -0,0 +1,172 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRenderLookup(t *testing.T) {
+	pods := []PodInfo{
+		{Name: "aeron-1", IP: "10.0.0.2", Port: 8050, ResolverName: "aeron-1.test-namespace.aeron"},
+		{Name: "aeron-0", IP: "fd00::1", Port: 9050, ResolverName: "aeron-0.test-namespace.aeron"},
+		// A stale duplicate of aeron-1, and a pod without a name, are left out
+		{Name: "aeron-1", IP: "10.0.0.9", Port: 8050, ResolverName: "aeron-1.test-namespace.aeron"},
+		{Name: "aeron-2", IP: "10.0.0.3", Port: 8050},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   LookupHosts,
+			expected: "fd00::1 aeron-0.test-namespace.aeron\n10.0.0.2 aeron-1.test-namespace.aeron\n",
+		},
+		{
+			format:   LookupProperties,
+			expected: "aeron-0.test-namespace.aeron=[fd00::1]:9050\naeron-1.test-namespace.aeron=10.0.0.2:8050\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if result := RenderLookup(tt.format, pods); result != tt.expected {
+				t.Errorf("RenderLookup() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderLookupLeavesOutPredictedMembersFromHosts(t *testing.T) {
+	pods := []PodInfo{
+		{Name: "aeron-0", IP: "aeron-0.aeron-headless.test-namespace.svc.cluster.local", Port: 8050, ResolverName: "aeron-0.test-namespace.aeron"},
+		{Name: "aeron-1", IP: "10.0.0.2", Port: 8050, ResolverName: "aeron-1.test-namespace.aeron"},
+	}
+	if result := RenderLookup(LookupHosts, pods); result != "10.0.0.2 aeron-1.test-namespace.aeron\n" {
+		t.Errorf("RenderLookup() = %q, expected only aeron-1", result)
+	}
+	expected := "aeron-0.test-namespace.aeron=aeron-0.aeron-headless.test-namespace.svc.cluster.local:8050\n" +
+		"aeron-1.test-namespace.aeron=10.0.0.2:8050\n"
+	if result := RenderLookup(LookupProperties, pods); result != expected {
+		t.Errorf("RenderLookup() = %q, expected %q", result, expected)
+	}
+}
+
+func TestRunWritesLookupFile(t *testing.T) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	self.Namespace = "test-namespace"
+	peer := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	peer.Namespace = "test-namespace"
+	peer.Annotations = map[string]string{ResolverNameAnnotation: "pricing-1.aeron", ResolverPortAnnotation: "9050"}
+	clientset := fake.NewSimpleClientset(&self, &peer)
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.Lookup = LookupProperties
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+	expected := "aeron-0.test-namespace.aeron=10.0.0.1:8050\npricing-1.aeron=10.0.0.2:9050\n"
+	content, err := os.ReadFile(filepath.Join(filepath.Dir(opts.BootstrapPath), "aeron-names.properties"))
+	if err != nil || string(content) != expected {
+		t.Errorf("Lookup file = (%q, %v), expected %q", content, err, expected)
+	}
+
+	result, err = Run(context.TODO(), opts)
+	if err != nil || result.Written {
+		t.Errorf("Unchanged Run() = (%v, %v), expected (false, nil)", result.Written, err)
+	}
+
+	// Only the lookup changes, which still counts as a write
+	opts.LookupPath = filepath.Join(t.TempDir(), "hosts")
+	opts.Lookup = LookupHosts
+	result, err = Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Errorf("Run() with a new lookup = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+	expected = "10.0.0.1 aeron-0.test-namespace.aeron\n10.0.0.2 pricing-1.aeron\n"
+	if content, err := os.ReadFile(opts.LookupPath); err != nil || string(content) != expected {
+		t.Errorf("Lookup file = (%q, %v), expected %q", content, err, expected)
+	}
+}
+
+func TestRunConfigMapOutputWithLookup(t *testing.T) {
+	clientset, opts := outputTestSetup(t, OutputConfigMap)
+	opts.Lookup = LookupHosts
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+	configMap, err := clientset.CoreV1().ConfigMaps("test-namespace").Get(context.TODO(), "aeron-0-aeron-bootstrap", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected the ConfigMap to be applied: %v", err)
+	}
+	if lookup := configMap.Data["aeron-names.hosts"]; lookup != "10.0.0.1 aeron-0.test-namespace.aeron\n" {
+		t.Errorf("ConfigMap lookup = %q, expected our own entry", lookup)
+	}
+	if _, err := os.Stat(filepath.Dir(opts.BootstrapPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected no lookup file with ConfigMap output, stat error = %v", err)
+	}
+}
+
+func TestRunLookupListsPeersBeyondMaxPods(t *testing.T) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now())
+	self.Namespace = "test-namespace"
+	objects := []runtime.Object{&self}
+	for i := 1; i <= 3; i++ {
+		peer := createTestPod(fmt.Sprintf("aeron-%d", i), fmt.Sprintf("10.0.0.%d", i+1), "Running", time.Now().Add(time.Duration(-i)*time.Minute))
+		peer.Namespace = "test-namespace"
+		objects = append(objects, &peer)
+	}
+
+	opts := testOptions(fake.NewSimpleClientset(objects...))
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.Lookup = LookupHosts
+	opts.MaxPods = 1
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(result.Neighbors) != 1 || result.Neighbors[0].Name != "aeron-3" {
+		t.Errorf("Run() neighbors = %v, expected only the oldest, aeron-3", result.Neighbors)
+	}
+	expected := "10.0.0.1 aeron-0.test-namespace.aeron\n10.0.0.2 aeron-1.test-namespace.aeron\n" +
+		"10.0.0.3 aeron-2.test-namespace.aeron\n10.0.0.4 aeron-3.test-namespace.aeron\n"
+	if result.Lookup != expected {
+		t.Errorf("Lookup = %q, expected every peer and ourselves %q", result.Lookup, expected)
+	}
+}
@@ -0,0 +1,177 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// skipReasons lists every reason a pod can be skipped, so each is exported even before it is first seen
+var skipReasons = []string{
+	SkipReasonMissingNetworkStatus,
+	SkipReasonInvalidNetworks,
+	SkipReasonInvalidNetworkStatus,
+	SkipReasonNetworkWithoutIP,
+	SkipReasonNetworkNotInStatus,
+	SkipReasonInvalidResolverName,
+}
+
+// Metrics holds the state exported on /metrics, and the readiness reported on /readyz
+// A nil *Metrics records nothing
+type Metrics struct {
+	mu                sync.Mutex
+	eligibleNeighbors int
+	skippedPods       map[string]int
+	apiErrors         map[string]int
+	lastWrite         time.Time
+	writes            int
+	rewrites          int
+}
+
+// NewMetrics creates empty metrics, with every skip reason exported from the start
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		skippedPods: make(map[string]int),
+		apiErrors:   make(map[string]int),
+	}
+	for _, reason := range skipReasons {
+		m.skippedPods[reason] = 0
+	}
+	return m
+}
+
+// observeDiscovery records the outcome of the latest neighbor discovery
+func (m *Metrics) observeDiscovery(pods []PodInfo, skipped []PodSkip) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.eligibleNeighbors = len(pods)
+	for reason := range m.skippedPods {
+		m.skippedPods[reason] = 0
+	}
+	for _, skip := range skipped {
+		m.skippedPods[skip.Reason]++
+	}
+}
+
+// observeAPIError counts a failed Kubernetes API call
+func (m *Metrics) observeAPIError(operation string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiErrors[operation]++
+}
+
+// observeWrite records a successful bootstrap, whether or not the file content changed
+func (m *Metrics) observeWrite(changed bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if changed {
+		if m.writes > 0 {
+			m.rewrites++
+		}
+		m.writes++
+	}
+	m.lastWrite = time.Now()
+}
+
+// ready reports whether the bootstrap file has been written, or found already up to date, at least once
+func (m *Metrics) ready() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.lastWrite.IsZero()
+}
+
+// writeTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeHeader := func(name, metricType, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+	}
+	writeLabelled := func(name, label string, values map[string]int) {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, escapeLabelValue(key), values[key])
+		}
+	}
+
+	writeHeader("aeron_bootstrap_eligible_neighbors", "gauge", "Number of eligible bootstrap neighbors found by the last discovery.")
+	fmt.Fprintf(w, "aeron_bootstrap_eligible_neighbors %d\n", m.eligibleNeighbors)
+
+	writeHeader("aeron_bootstrap_skipped_pods", "gauge", "Number of pods skipped by Multus or resolver name validation in the last discovery, by reason.")
+	writeLabelled("aeron_bootstrap_skipped_pods", "reason", m.skippedPods)
+
+	writeHeader("aeron_bootstrap_api_errors_total", "counter", "Number of failed Kubernetes API calls, by operation.")
+	writeLabelled("aeron_bootstrap_api_errors_total", "operation", m.apiErrors)
+
+	writeHeader("aeron_bootstrap_last_write_timestamp_seconds", "gauge", "Unix time the bootstrap file was last successfully written, or confirmed up to date.")
+	var lastWrite float64
+	if !m.lastWrite.IsZero() {
+		lastWrite = float64(m.lastWrite.UnixNano()) / 1e9
+	}
+	fmt.Fprintf(w, "aeron_bootstrap_last_write_timestamp_seconds %g\n", lastWrite)
+
+	writeHeader("aeron_bootstrap_rewrites_total", "counter", "Number of times the bootstrap file was rewritten with changed content after the first write.")
+	fmt.Fprintf(w, "aeron_bootstrap_rewrites_total %d\n", m.rewrites)
+}
+
+// escapeLabelValue escapes a Prometheus label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Handler serves /metrics, /healthz and /readyz
+// Readiness stays false until the first bootstrap file has been written
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.writeTo(w)
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !m.ready() {
+			http.Error(w, "bootstrap file not written yet", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
@@ -0,0 +1,161 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetricsHandlerReadiness(t *testing.T) {
+	m := NewMetrics()
+	handler := m.Handler()
+
+	get := func(path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz = %d, expected %d", code, http.StatusOK)
+	}
+	if code := get("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before first write = %d, expected %d", code, http.StatusServiceUnavailable)
+	}
+
+	m.observeWrite(true)
+	if code := get("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz after first write = %d, expected %d", code, http.StatusOK)
+	}
+}
+
+func TestMetricsExposition(t *testing.T) {
+	m := NewMetrics()
+	m.observeDiscovery(
+		[]PodInfo{{Name: "aeron-0"}, {Name: "aeron-1"}},
+		[]PodSkip{{Name: "aeron-2", Reason: SkipReasonMissingNetworkStatus}, {Name: "aeron-3", Reason: SkipReasonMissingNetworkStatus}},
+	)
+	m.observeAPIError("list_pods")
+	m.observeWrite(true)
+	m.observeWrite(false)
+	m.observeWrite(true)
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	output := recorder.Body.String()
+
+	expectedLines := []string{
+		"# TYPE aeron_bootstrap_eligible_neighbors gauge",
+		"aeron_bootstrap_eligible_neighbors 2",
+		`aeron_bootstrap_skipped_pods{reason="MissingNetworkStatus"} 2`,
+		`aeron_bootstrap_skipped_pods{reason="NetworkNotInStatus"} 0`,
+		`aeron_bootstrap_skipped_pods{reason="InvalidResolverName"} 0`,
+		`aeron_bootstrap_api_errors_total{operation="list_pods"} 1`,
+		"aeron_bootstrap_rewrites_total 1",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected metrics output to contain %q, got:\n%s", line, output)
+		}
+	}
+	if strings.Contains(output, "aeron_bootstrap_last_write_timestamp_seconds 0\n") {
+		t.Errorf("Expected last write timestamp to be set, got:\n%s", output)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if result := escapeLabelValue("a\"b\\c\nd"); result != `a\"b\\c\nd` {
+		t.Errorf("escapeLabelValue() = %s", result)
+	}
+}
+
+func TestRunRewritesOnlyOnChange(t *testing.T) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	self.Namespace = "test-namespace"
+	clientset := fake.NewSimpleClientset(&self)
+
+	metrics := NewMetrics()
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "aeron", "bootstrap.properties")
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+	opts.Metrics = metrics
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("First Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+	if !metrics.ready() {
+		t.Errorf("Expected metrics to be ready after the first write")
+	}
+
+	result, err = Run(context.TODO(), opts)
+	if err != nil || result.Written {
+		t.Fatalf("Unchanged Run() = (%v, %v), expected (false, nil)", result.Written, err)
+	}
+
+	peer := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	if _, err := clientset.CoreV1().Pods("test-namespace").Create(context.TODO(), &peer, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create test pod: %v", err)
+	}
+
+	result, err = Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("Changed Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+	if metrics.rewrites != 1 || metrics.eligibleNeighbors != 2 {
+		t.Errorf("Expected 1 rewrite and 2 eligible neighbors, got %d and %d", metrics.rewrites, metrics.eligibleNeighbors)
+	}
+}
+
+func TestRunNoNeighbors(t *testing.T) {
+	self := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "aeron-0", Namespace: "test-namespace"}}
+
+	metrics := NewMetrics()
+	opts := testOptions(fake.NewSimpleClientset(&self))
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.EmitEvents = false
+	opts.Metrics = metrics
+
+	_, err := Run(context.TODO(), opts)
+	if !errors.Is(err, ErrNoPeers) {
+		t.Errorf("Run() error = %v, expected %v", err, ErrNoPeers)
+	}
+	if metrics.ready() {
+		t.Errorf("Expected metrics not to be ready when no file was written")
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	m.observeDiscovery([]PodInfo{{Name: "aeron-0"}}, nil)
+	m.observeAPIError("list_pods")
+	m.observeWrite(true)
+}
@@ -0,0 +1,478 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Multus annotations, and the interface name Multus gives the first secondary network
+const (
+	NetworkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+	NetworksAnnotation      = "k8s.v1.cni.cncf.io/networks"
+
+	DefaultSecondaryInterfaceName = "net1"
+)
+
+// Reasons a candidate pod is skipped by Multus validation
+const (
+	SkipReasonMissingNetworkStatus = "MissingNetworkStatus"
+	SkipReasonInvalidNetworks      = "InvalidNetworksAnnotation"
+	SkipReasonInvalidNetworkStatus = "InvalidNetworkStatus"
+	SkipReasonNetworkWithoutIP     = "NetworkWithoutIP"
+	SkipReasonNetworkNotInStatus   = "NetworkNotInStatus"
+)
+
+// Where SelectIP, PublishedIP or a host-network pod's Node provided a pod's address
+const (
+	IPSourcePodIP            = "PodIP"
+	IPSourceNetworkName      = "NetworkName"
+	IPSourceInterfaceName    = "InterfaceName"
+	IPSourceDefaultInterface = "DefaultInterface"
+	IPSourcePodIPFallback    = "PodIPFallback"
+	IPSourcePublished        = "Published"
+	IPSourceHostNetwork      = "HostNetwork"
+	IPSourceNodeAddress      = "NodeAddress"
+	IPSourceNodeAnnotation   = "NodeAnnotation"
+)
+
+// NetworkStatus is one entry of the Multus network-status annotation
+type NetworkStatus struct {
+	Name      string   `json:"name"`
+	Interface string   `json:"interface"`
+	IPs       []string `json:"ips,omitempty"`
+	MAC       string   `json:"mac,omitempty"`
+	Default   bool     `json:"default,omitempty"`
+	DNS       struct{} `json:"dns"`
+}
+
+// SecondaryInterface selects which Multus network a pod's address is taken from
+// An empty field is not matched on, the default secondary interface net1 is always matched
+type SecondaryInterface struct {
+	NetworkName   string
+	InterfaceName string
+	// IPSelection chooses between the addresses of a network carrying several, see ParseIPSelection
+	IPSelection string
+}
+
+// PodSecondaryInterface returns the network a pod's resolver address is taken from: the aeron.io/network and
+// aeron.io/network-interface annotations if the pod declares either, otherwise defaults
+// A pod declaring only one of them is not matched on the other, so a default network name can't take precedence
+// over the interface it declared
+func PodSecondaryInterface(pod v1.Pod, defaults SecondaryInterface) SecondaryInterface {
+	network := strings.TrimSpace(pod.Annotations[NetworkAnnotation])
+	iface := strings.TrimSpace(pod.Annotations[NetworkInterfaceAnnotation])
+	if network == "" && iface == "" {
+		return defaults
+	}
+	secondary := SecondaryInterface{NetworkName: network, InterfaceName: iface, IPSelection: defaults.IPSelection}
+	if secondary != defaults {
+		slog.Debug("Using the network declared by the pod", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+			"network", network, "interface", iface)
+	}
+	return secondary
+}
+
+// ParseNetworkStatus parses the network status annotation JSON into a slice of NetworkStatus
+func ParseNetworkStatus(annotation string) ([]NetworkStatus, error) {
+	var networks []NetworkStatus
+	err := json.Unmarshal([]byte(annotation), &networks)
+	if err != nil {
+		if err.Error() == "unexpected end of JSON input" {
+			// Empty annotation, return empty slice
+			return networks, nil
+		}
+		return nil, fmt.Errorf("error unmarshaling network status: %v", err)
+	}
+	return networks, nil
+}
+
+// NetworkSelectionElement is one network a pod requests in the k8s.v1.cni.cncf.io/networks annotation,
+// as defined by the Kubernetes Network Plumbing Working Group's multi-network spec
+type NetworkSelectionElement struct {
+	// Name of the NetworkAttachmentDefinition
+	Name string `json:"name"`
+	// Namespace of the NetworkAttachmentDefinition, empty for the pod's own
+	Namespace string `json:"namespace,omitempty"`
+	// IPs requested for the interface, as addresses or CIDRs
+	IPs []string `json:"ips,omitempty"`
+	// MAC requested for the interface
+	MAC string `json:"mac,omitempty"`
+	// Interface name requested, empty to let Multus name it net<index+1>
+	Interface string `json:"interface,omitempty"`
+	// DefaultRoute lists gateways to install the pod's default route through
+	DefaultRoute []string `json:"default-route,omitempty"`
+	// CNIArgs are passed through to the CNI plugin
+	CNIArgs map[string]any `json:"cni-args,omitempty"`
+}
+
+// maxInterfaceNameLength is the longest Linux network interface name
+const maxInterfaceNameLength = 15
+
+// ParseNetworkSelectionElements parses the k8s.v1.cni.cncf.io/networks annotation, in either of its forms:
+// - A JSON array of network selection elements: [{"name":"mynet","namespace":"ns","interface":"aeron0"}]
+// - Comma-separated short forms of [<namespace>/]<name>[@<interface>]: "mynet1,ns/mynet2@aeron0"
+func ParseNetworkSelectionElements(annotation string) ([]NetworkSelectionElement, error) {
+	annotation = strings.TrimSpace(annotation)
+	if annotation == "" {
+		return nil, nil
+	}
+
+	var elements []NetworkSelectionElement
+	if strings.HasPrefix(annotation, "[") {
+		if err := json.Unmarshal([]byte(annotation), &elements); err != nil {
+			return nil, fmt.Errorf("invalid network selection elements: %v", err)
+		}
+	} else {
+		for _, item := range strings.Split(annotation, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			element, err := parseShortNetworkSelection(item)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+	}
+
+	for i, element := range elements {
+		if err := element.validate(); err != nil {
+			return nil, fmt.Errorf("network %d: %w", i+1, err)
+		}
+	}
+	return elements, nil
+}
+
+// parseShortNetworkSelection parses the [<namespace>/]<name>[@<interface>] short form of a network selection element
+func parseShortNetworkSelection(item string) (NetworkSelectionElement, error) {
+	var element NetworkSelectionElement
+	rest := item
+	if namespace, name, ok := strings.Cut(rest, "/"); ok {
+		element.Namespace, rest = namespace, name
+	}
+	if name, iface, ok := strings.Cut(rest, "@"); ok {
+		rest, element.Interface = name, iface
+		if element.Interface == "" {
+			return element, fmt.Errorf("network %q has an empty interface name", item)
+		}
+	}
+	element.Name = rest
+	if strings.ContainsAny(element.Name, "/@") || strings.ContainsAny(element.Namespace, "@") || strings.ContainsAny(element.Interface, "/@") {
+		return element, fmt.Errorf("network %q is not of the form [<namespace>/]<name>[@<interface>]", item)
+	}
+	return element, nil
+}
+
+// validate checks a network selection element is well formed
+func (e NetworkSelectionElement) validate() error {
+	if errs := validation.IsDNS1123Subdomain(e.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", e.Name, strings.Join(errs, ", "))
+	}
+	if e.Namespace != "" {
+		if errs := validation.IsDNS1123Label(e.Namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %q: %s", e.Namespace, strings.Join(errs, ", "))
+		}
+	}
+	if e.Interface != "" && (len(e.Interface) > maxInterfaceNameLength || strings.ContainsAny(e.Interface, "/: \t\n") ||
+		e.Interface == "." || e.Interface == "..") {
+		return fmt.Errorf("invalid interface name %q", e.Interface)
+	}
+	for _, ip := range e.IPs {
+		if net.ParseIP(ip) == nil {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return fmt.Errorf("invalid IP %q", ip)
+			}
+		}
+	}
+	if e.MAC != "" {
+		if _, err := net.ParseMAC(e.MAC); err != nil {
+			return fmt.Errorf("invalid MAC %q", e.MAC)
+		}
+	}
+	for _, gateway := range e.DefaultRoute {
+		if net.ParseIP(gateway) == nil {
+			return fmt.Errorf("invalid default-route gateway %q", gateway)
+		}
+	}
+	return nil
+}
+
+// describe returns the element in its short form, for messages
+func (e NetworkSelectionElement) describe() string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + name
+	}
+	if e.Interface != "" {
+		name += "@" + e.Interface
+	}
+	return name
+}
+
+// InterfaceName returns the interface Multus gives the element at index in the annotation, its requested
+// interface or net<index+1>
+func (e NetworkSelectionElement) InterfaceName(index int) string {
+	if e.Interface != "" {
+		return e.Interface
+	}
+	return fmt.Sprintf("net%d", index+1)
+}
+
+// MatchesName returns whether the element requests the network called name, either <name> in the pod's own namespace,
+// or <namespace>/<name>
+func (e NetworkSelectionElement) MatchesName(name, podNamespace string) bool {
+	namespace := e.Namespace
+	if namespace == "" {
+		namespace = podNamespace
+	}
+	if namespace == podNamespace && name == e.Name {
+		return true
+	}
+	return name == namespace+"/"+e.Name
+}
+
+// MatchesStatus returns whether a network-status entry reports the network the element requests, on its requested interface
+func (e NetworkSelectionElement) MatchesStatus(status NetworkStatus, podNamespace string) bool {
+	if e.Interface != "" && status.Interface != e.Interface {
+		return false
+	}
+	return e.MatchesName(status.Name, podNamespace)
+}
+
+// ParseNetworksAnnotation extracts network names from the k8s.v1.cni.cncf.io/networks annotation,
+// in either form parsed by ParseNetworkSelectionElements, qualified by namespace when the element names one
+func ParseNetworksAnnotation(annotation string) ([]string, error) {
+	elements, err := ParseNetworkSelectionElements(annotation)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, element := range elements {
+		if element.Namespace != "" {
+			names = append(names, element.Namespace+"/"+element.Name)
+		} else {
+			names = append(names, element.Name)
+		}
+	}
+	return names, nil
+}
+
+// ValidateMultusNetworkStatus checks if a pod with Multus network annotations has valid network status
+// Returns true if the pod is valid for bootstrap, false if it should be skipped
+func ValidateMultusNetworkStatus(pod v1.Pod) bool {
+	if skip := CheckMultusNetworkStatus(pod); skip != nil {
+		logSkip(pod, skip)
+		return false
+	}
+	return true
+}
+
+// logSkip logs why a pod was skipped as a bootstrap candidate
+func logSkip(pod v1.Pod, skip *PodSkip) {
+	slog.Warn("Skipping pod as bootstrap candidate", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+		LogKeyReason, skip.Reason, "detail", fmt.Sprintf("Pod %s %s", pod.Name, skip.Detail))
+}
+
+// CheckMultusNetworkStatus returns why a pod with Multus network annotations should be skipped as a
+// bootstrap candidate, or nil if it is valid
+func CheckMultusNetworkStatus(pod v1.Pod) *PodSkip {
+	networksAnnotation := pod.Annotations[NetworksAnnotation]
+
+	// If no networks annotation, pod is valid
+	if networksAnnotation == "" {
+		return nil
+	}
+
+	skip := func(reason, format string, args ...any) *PodSkip {
+		return &PodSkip{Name: pod.Name, Namespace: pod.Namespace, Reason: reason, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	// Pod has networks annotation, so it must also have network-status
+	networkStatusAnnotation := pod.Annotations[NetworkStatusAnnotation]
+	if networkStatusAnnotation == "" {
+		return skip(SkipReasonMissingNetworkStatus, "has %s annotation but missing %s annotation",
+			NetworksAnnotation, NetworkStatusAnnotation)
+	}
+
+	// Parse the networks annotation to get the expected networks
+	expectedNetworks, err := ParseNetworkSelectionElements(networksAnnotation)
+	if err != nil {
+		return skip(SkipReasonInvalidNetworks, "has invalid %s annotation format: %v", NetworksAnnotation, err)
+	}
+
+	// Parse the network status
+	networkStatuses, err := ParseNetworkStatus(networkStatusAnnotation)
+	if err != nil {
+		return skip(SkipReasonInvalidNetworkStatus, "has invalid %s annotation: %v", NetworkStatusAnnotation, err)
+	}
+
+	// Check that each expected network has a corresponding status with an IP, on its requested interface if any
+	for _, expectedNetwork := range expectedNetworks {
+		found := false
+		for _, status := range networkStatuses {
+			if expectedNetwork.MatchesStatus(status, pod.Namespace) {
+				if len(status.IPs) == 0 {
+					return skip(SkipReasonNetworkWithoutIP, "has network %s in status but no IP address", expectedNetwork.describe())
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return skip(SkipReasonNetworkNotInStatus, "has network %s in %s but not in %s",
+				expectedNetwork.describe(), NetworksAnnotation, NetworkStatusAnnotation)
+		}
+	}
+
+	return nil
+}
+
+// SelectIP retrieves the IP address for the secondary interface from the pod's network status annotation,
+// falling back to the primary PodIP if no secondary interface is found
+// Of several addresses, the one chosen by secondary.IPSelection is used, skipping networks without a matching address
+// It also returns where the address was found, one of the IPSource constants
+func SelectIP(pod v1.Pod, secondary SecondaryInterface) (string, string, error) {
+	selection, err := ParseIPSelection(secondary.IPSelection)
+	if err != nil {
+		return "", "", err
+	}
+
+	var networks []NetworkStatus
+	networks, err = ParseNetworkStatus(pod.Annotations[NetworkStatusAnnotation])
+	if err != nil {
+		slog.Error("Error parsing network status", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, "error", err)
+		return "", "", err
+	}
+
+	if len(networks) == 0 {
+		ip := selection.podIP(pod)
+		slog.Debug("No network status annotation found, using status.PodIP", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+			LogKeyIP, ip, LogKeyReason, IPSourcePodIP)
+		return ip, IPSourcePodIP, nil
+	}
+
+	// The networks requested say which interface a network was attached on, if it was named
+	requested, _ := ParseNetworkSelectionElements(pod.Annotations[NetworksAnnotation])
+
+	// A configured network name takes precedence over a configured interface name, which takes precedence over net1,
+	// wherever they are in the network status
+	tiers := []struct {
+		source  string
+		matches func(NetworkStatus) bool
+		message string
+		attr    []any
+	}{
+		{
+			source: IPSourceNetworkName,
+			matches: func(network NetworkStatus) bool {
+				return secondary.NetworkName != "" && statusMatchesNetwork(network, secondary.NetworkName, pod.Namespace, requested)
+			},
+			message: "Secondary network name is set, found network",
+			attr:    []any{"network", secondary.NetworkName},
+		},
+		{
+			source: IPSourceInterfaceName,
+			matches: func(network NetworkStatus) bool {
+				return secondary.InterfaceName != "" && network.Interface == secondary.InterfaceName
+			},
+			message: "Secondary interface name is set, found interface",
+			attr:    []any{"interface", secondary.InterfaceName},
+		},
+		{
+			source: IPSourceDefaultInterface,
+			matches: func(network NetworkStatus) bool {
+				return network.Interface == DefaultSecondaryInterfaceName
+			},
+			message: "No secondary interface or network is set, found default secondary interface",
+			attr:    []any{"interface", DefaultSecondaryInterfaceName},
+		},
+	}
+	for _, tier := range tiers {
+		for _, network := range networks {
+			if !tier.matches(network) {
+				continue
+			}
+			ip, ok := selection.Select(network.IPs)
+			if !ok {
+				slog.Debug("Network has no address matching the IP selection", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+					"network", network.Name, "interface", network.Interface, "ips", network.IPs, "ipSelection", selection.String())
+				continue
+			}
+			slog.Debug(tier.message, append([]any{LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+				LogKeyIP, ip, LogKeyReason, tier.source}, tier.attr...)...)
+			return ip, tier.source, nil
+		}
+	}
+
+	ip := selection.podIP(pod)
+	slog.Warn("network-status annotation was found, but no network matched. Falling back to using its primary interface (status.PodIP)",
+		LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, LogKeyIP, ip, LogKeyReason, IPSourcePodIPFallback, "interface", DefaultSecondaryInterfaceName)
+	return ip, IPSourcePodIPFallback, nil
+}
+
+// statusMatchesNetwork returns whether a network-status entry reports the named network, given as <name> in the pod's
+// namespace or <namespace>/<name>, on one of the interfaces the pod requested it on, if it named any
+func statusMatchesNetwork(status NetworkStatus, name, podNamespace string, requested []NetworkSelectionElement) bool {
+	if status.Name != name && status.Name != podNamespace+"/"+name {
+		return false
+	}
+	var interfaces []string
+	for _, element := range requested {
+		if element.Interface != "" && element.MatchesName(name, podNamespace) {
+			interfaces = append(interfaces, element.Interface)
+		}
+	}
+	return len(interfaces) == 0 || slices.Contains(interfaces, status.Interface)
+}
+
+// PublishedIP returns the address a pod published for itself via the aeron.io/published-address annotation,
+// or was given via the aeron.io/resolver-address override, or an empty string if it has neither valid
+// A bootstrapped pod publishes its override, so the two only differ until it next refreshes
+func PublishedIP(pod v1.Pod) (string, string) {
+	for _, annotation := range []string{PublishedAddressAnnotation, ResolverAddressAnnotation} {
+		if ip := annotatedIP(pod, annotation); ip != "" {
+			return ip, IPSourcePublished
+		}
+	}
+	return "", ""
+}
+
+// annotatedIP returns the address held in one of a pod's annotations, or an empty string if it is absent or invalid
+func annotatedIP(pod v1.Pod, annotation string) string {
+	address, ok := pod.Annotations[annotation]
+	if !ok {
+		return ""
+	}
+	ip := net.ParseIP(strings.TrimSpace(address))
+	if ip == nil {
+		slog.Warn("Ignoring invalid annotation", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, "annotation", annotation, "value", address)
+		return ""
+	}
+	slog.Debug("Using annotated resolver address", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace, LogKeyIP, ip.String(),
+		LogKeyReason, IPSourcePublished, "annotation", annotation)
+	return ip.String()
+}
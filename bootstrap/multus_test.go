@@ -0,0 +1,586 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPublishedIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		expectedIP     string
+		expectedSource string
+	}{
+		{
+			name:       "no annotation",
+			expectedIP: "",
+		},
+		{
+			name:           "valid IPv4 address",
+			annotations:    map[string]string{"aeron.io/resolver-address": "192.168.1.200"},
+			expectedIP:     "192.168.1.200",
+			expectedSource: IPSourcePublished,
+		},
+		{
+			name:           "valid IPv6 address",
+			annotations:    map[string]string{"aeron.io/resolver-address": "fd00::1"},
+			expectedIP:     "fd00::1",
+			expectedSource: IPSourcePublished,
+		},
+		{
+			name:        "invalid address ignored",
+			annotations: map[string]string{"aeron.io/resolver-address": "not-an-ip"},
+			expectedIP:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := createTestPod("aeron-1", "10.0.0.1", "Running", time.Now())
+			pod.Annotations = tt.annotations
+
+			ip, source := PublishedIP(pod)
+			if ip != tt.expectedIP || source != tt.expectedSource {
+				t.Errorf("PublishedIP() = (%s, %s), expected (%s, %s)", ip, source, tt.expectedIP, tt.expectedSource)
+			}
+		})
+	}
+}
+
+func TestSelectIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		expected string
+	}{
+		{
+			name:     "pod with primary IP",
+			pod:      createTestPod("pod-with-primary", "10.0.0.1", "Running", time.Now().Add(-5*time.Minute)),
+			expected: "10.0.0.1",
+		},
+		{
+			name:     "pod with secondary interface",
+			pod:      createTestPodWithSecondaryInterface("pod-with-secondary", "10.0.0.1", "10.0.0.2", "Running", "custom-network", "net1", time.Now().Add(-5*time.Minute)),
+			expected: "10.0.0.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _, _ := SelectIP(tt.pod, SecondaryInterface{})
+			if result != tt.expected {
+				t.Errorf("SelectIP() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseNetworksAnnotation(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotation  string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:        "empty annotation",
+			annotation:  "",
+			expected:    nil,
+			expectError: false,
+		},
+		{
+			name:        "simple string",
+			annotation:  "mynet",
+			expected:    []string{"mynet"},
+			expectError: false,
+		},
+		{
+			name:        "comma-separated",
+			annotation:  "mynet1,mynet2",
+			expected:    []string{"mynet1", "mynet2"},
+			expectError: false,
+		},
+		{
+			name:        "comma-separated with spaces",
+			annotation:  "mynet1, mynet2, mynet3",
+			expected:    []string{"mynet1", "mynet2", "mynet3"},
+			expectError: false,
+		},
+		{
+			name:        "JSON array format",
+			annotation:  `[{"name":"mynet1"},{"name":"mynet2"}]`,
+			expected:    []string{"mynet1", "mynet2"},
+			expectError: false,
+		},
+		{
+			name:        "JSON array format with single network",
+			annotation:  `[{"name":"custom-network"}]`,
+			expected:    []string{"custom-network"},
+			expectError: false,
+		},
+		{
+			name:        "short form with namespace and interface",
+			annotation:  "other-ns/mynet1@aeron0, mynet2@eth9",
+			expected:    []string{"other-ns/mynet1", "mynet2"},
+			expectError: false,
+		},
+		{
+			name:        "JSON array format with namespace",
+			annotation:  `[{"name":"mynet1","namespace":"other-ns"}]`,
+			expected:    []string{"other-ns/mynet1"},
+			expectError: false,
+		},
+		{
+			name:        "invalid JSON",
+			annotation:  `[{"name":"mynet1"}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseNetworksAnnotation(tt.annotation)
+
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+				return
+			}
+
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if len(result) != len(tt.expected) {
+				t.Errorf("Expected %d networks, got %d", len(tt.expected), len(result))
+				return
+			}
+
+			for i, name := range result {
+				if name != tt.expected[i] {
+					t.Errorf("Network %d: expected %s, got %s", i, tt.expected[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestPodSecondaryInterface(t *testing.T) {
+	defaults := SecondaryInterface{NetworkName: "old-net", InterfaceName: "net1"}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    SecondaryInterface
+	}{
+		{name: "no annotations", expected: defaults},
+		{name: "blank annotation", annotations: map[string]string{NetworkAnnotation: " "}, expected: defaults},
+		{name: "network", annotations: map[string]string{NetworkAnnotation: "new-net"}, expected: SecondaryInterface{NetworkName: "new-net"}},
+		{name: "interface", annotations: map[string]string{NetworkInterfaceAnnotation: "aeron0"}, expected: SecondaryInterface{InterfaceName: "aeron0"}},
+		{
+			name:        "both",
+			annotations: map[string]string{NetworkAnnotation: "new-ns/new-net", NetworkInterfaceAnnotation: "aeron0"},
+			expected:    SecondaryInterface{NetworkName: "new-ns/new-net", InterfaceName: "aeron0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "aeron-0", Annotations: tt.annotations}}
+			if result := PodSecondaryInterface(pod, defaults); result != tt.expected {
+				t.Errorf("PodSecondaryInterface() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseNetworkSelectionElements(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotation  string
+		expected    []NetworkSelectionElement
+		expectError string
+	}{
+		{
+			name:       "short forms",
+			annotation: "mynet1,other-ns/mynet2,mynet3@aeron0,other-ns/mynet4@aeron1",
+			expected: []NetworkSelectionElement{
+				{Name: "mynet1"},
+				{Name: "mynet2", Namespace: "other-ns"},
+				{Name: "mynet3", Interface: "aeron0"},
+				{Name: "mynet4", Namespace: "other-ns", Interface: "aeron1"},
+			},
+		},
+		{
+			name: "every JSON field",
+			annotation: `[{"name":"mynet","namespace":"other-ns","ips":["192.168.1.10/24","fd00::10"],"mac":"02:4a:ef:75:4e:00",
+				"interface":"aeron0","default-route":["192.168.1.1"],"cni-args":{"mtu":9000}}]`,
+			expected: []NetworkSelectionElement{{
+				Name:         "mynet",
+				Namespace:    "other-ns",
+				IPs:          []string{"192.168.1.10/24", "fd00::10"},
+				MAC:          "02:4a:ef:75:4e:00",
+				Interface:    "aeron0",
+				DefaultRoute: []string{"192.168.1.1"},
+				CNIArgs:      map[string]any{"mtu": float64(9000)},
+			}},
+		},
+		{name: "empty annotation", annotation: "  "},
+		{name: "too many namespaces", annotation: "a/b/c", expectError: "not of the form"},
+		{name: "too many interfaces", annotation: "mynet@aeron0@aeron1", expectError: "not of the form"},
+		{name: "empty interface", annotation: "mynet@", expectError: "empty interface"},
+		{name: "invalid name", annotation: "My_Net", expectError: "invalid name"},
+		{name: "missing JSON name", annotation: `[{"interface":"aeron0"}]`, expectError: "invalid name"},
+		{name: "invalid namespace", annotation: "my.ns/mynet", expectError: "invalid namespace"},
+		{name: "interface name too long", annotation: "mynet@aeron-interface0", expectError: "invalid interface"},
+		{name: "invalid IP", annotation: `[{"name":"mynet","ips":["192.168.1"]}]`, expectError: "invalid IP"},
+		{name: "invalid MAC", annotation: `[{"name":"mynet","mac":"02:4a"}]`, expectError: "invalid MAC"},
+		{name: "invalid gateway", annotation: `[{"name":"mynet","default-route":["gateway"]}]`, expectError: "invalid default-route"},
+		{name: "wrong JSON type", annotation: `[{"name":1}]`, expectError: "invalid network selection elements"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseNetworkSelectionElements(tt.annotation)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("ParseNetworkSelectionElements() error = %v, expected %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseNetworkSelectionElements() error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseNetworkSelectionElements() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNetworkSelectionElementMatchesStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		element  NetworkSelectionElement
+		status   NetworkStatus
+		expected bool
+	}{
+		{"bare name", NetworkSelectionElement{Name: "aeron"}, NetworkStatus{Name: "aeron", Interface: "net1"}, true},
+		{"own namespace", NetworkSelectionElement{Name: "aeron"}, NetworkStatus{Name: "test-ns/aeron", Interface: "net1"}, true},
+		{"explicit own namespace", NetworkSelectionElement{Name: "aeron", Namespace: "test-ns"}, NetworkStatus{Name: "aeron"}, true},
+		{"other namespace", NetworkSelectionElement{Name: "aeron", Namespace: "other-ns"}, NetworkStatus{Name: "other-ns/aeron"}, true},
+		{"other namespace in status only", NetworkSelectionElement{Name: "aeron"}, NetworkStatus{Name: "other-ns/aeron"}, false},
+		{"other namespace bare status", NetworkSelectionElement{Name: "aeron", Namespace: "other-ns"}, NetworkStatus{Name: "aeron"}, false},
+		{"requested interface", NetworkSelectionElement{Name: "aeron", Interface: "aeron0"}, NetworkStatus{Name: "aeron", Interface: "aeron0"}, true},
+		{"other interface", NetworkSelectionElement{Name: "aeron", Interface: "aeron0"}, NetworkStatus{Name: "aeron", Interface: "net1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.element.MatchesStatus(tt.status, "test-ns"); result != tt.expected {
+				t.Errorf("MatchesStatus() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSelectIPRequestedInterface(t *testing.T) {
+	// The same network attached twice, the Aeron one requested on aeron0
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "aeron-0",
+			Namespace: "test-ns",
+			Annotations: map[string]string{
+				NetworksAnnotation: "shared@net1,shared@aeron0",
+				NetworkStatusAnnotation: `[{"name":"test-ns/shared","interface":"net1","ips":["192.168.1.10"]},
+					{"name":"test-ns/shared","interface":"aeron0","ips":["192.168.2.10"]}]`,
+			},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+
+	tests := []struct {
+		name      string
+		secondary SecondaryInterface
+		expected  string
+	}{
+		{"network in own namespace", SecondaryInterface{NetworkName: "shared"}, "192.168.1.10"},
+		{"qualified network", SecondaryInterface{NetworkName: "test-ns/shared"}, "192.168.1.10"},
+		{"interface", SecondaryInterface{InterfaceName: "aeron0"}, "192.168.2.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ip, _, _ := SelectIP(pod, tt.secondary); ip != tt.expected {
+				t.Errorf("SelectIP() = %s, expected %s", ip, tt.expected)
+			}
+		})
+	}
+
+	// Requesting the network only on aeron0 selects that attachment, even though net1 is reported first
+	pod.Annotations[NetworksAnnotation] = `[{"name":"shared","interface":"aeron0"}]`
+	if ip, source, _ := SelectIP(pod, SecondaryInterface{NetworkName: "shared"}); ip != "192.168.2.10" || source != IPSourceNetworkName {
+		t.Errorf("SelectIP() = (%s, %s), expected the requested interface's address", ip, source)
+	}
+}
+
+func TestValidateMultusNetworkStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		expected bool
+	}{
+		{
+			name: "pod without networks annotation - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pod-no-multus",
+					Annotations: map[string]string{},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with networks annotation but no network-status - invalid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-missing-status",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks": "mynet",
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "pod with networks and matching network-status with IP - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-valid-multus",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "mynet",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"mynet","interface":"net1","ips":["10.0.0.2"]}]`,
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with networks but network-status missing that network - invalid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-wrong-network",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "mynet",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"othernet","interface":"net1","ips":["10.0.0.2"]}]`,
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "pod with networks and network-status but no IP - invalid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-no-ip",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "mynet",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"mynet","interface":"net1","ips":[]}]`,
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "pod with multiple networks all valid - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-multi-valid",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "net1,net2",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"net1","interface":"net1","ips":["10.0.0.1"]},{"name":"net2","interface":"net2","ips":["10.0.0.2"]}]`,
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with multiple networks but one missing - invalid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-multi-invalid",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "net1,net2",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"net1","interface":"net1","ips":["10.0.0.1"]}]`,
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "pod with JSON array networks format - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pod-json-format",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       `[{"name":"custom-net"}]`,
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"custom-net","interface":"net1","ips":["10.0.0.3"]}]`,
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with namespace-qualified network name in status - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-namespace-qualified",
+					Namespace: "test-ns",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "aeron",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"test-ns/aeron","interface":"net1","ips":["192.168.1.201"]}]`,
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with network on its requested interface - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-requested-interface",
+					Namespace: "test-ns",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "aeron@aeron0",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"test-ns/aeron","interface":"aeron0","ips":["192.168.1.201"]}]`,
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with network on another interface than requested - invalid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-other-interface",
+					Namespace: "test-ns",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       `[{"name":"aeron","interface":"aeron0"}]`,
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"test-ns/aeron","interface":"net1","ips":["192.168.1.201"]}]`,
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "pod with network from another namespace - valid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-other-namespace",
+					Namespace: "test-ns",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "shared-ns/aeron",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"shared-ns/aeron","interface":"net1","ips":["192.168.1.201"]}]`,
+					},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "pod with wrong namespace in qualified network name - invalid",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-wrong-namespace",
+					Namespace: "test-ns",
+					Annotations: map[string]string{
+						"k8s.v1.cni.cncf.io/networks":       "aeron",
+						"k8s.v1.cni.cncf.io/network-status": `[{"name":"other-ns/aeron","interface":"net1","ips":["192.168.1.201"]}]`,
+					},
+				},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidateMultusNetworkStatus(tt.pod)
+			if result != tt.expected {
+				t.Errorf("ValidateMultusNetworkStatus() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSkipLogsStructuredAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(previous)
+
+	pod := createTestPodWithInvalidMultus("aeron-1", "10.0.0.1", time.Now())
+	pod.Namespace = "test-namespace"
+	if ValidateMultusNetworkStatus(pod) {
+		t.Fatalf("Expected pod with missing network-status to be invalid")
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON log record, got %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]string{
+		LogKeyPod:       "aeron-1",
+		LogKeyNamespace: "test-namespace",
+		LogKeyReason:    SkipReasonMissingNetworkStatus,
+		"level":         "WARN",
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("Log attribute %s = %v, expected %s", key, record[key], value)
+		}
+	}
+}
+
+func TestSelectIPLogsStructuredAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(previous)
+
+	pod := createTestPodWithSecondaryInterface("aeron-1", "10.0.0.1", "10.0.0.2", "Running", "aeron-network", "net1", time.Now())
+	if _, _, err := SelectIP(pod, SecondaryInterface{}); err != nil {
+		t.Fatalf("SelectIP() error = %v", err)
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON log record, got %q: %v", buf.String(), err)
+	}
+	if record[LogKeyPod] != "aeron-1" || record[LogKeyIP] != "10.0.0.2" || record[LogKeyReason] != IPSourceDefaultInterface {
+		t.Errorf("Unexpected log attributes: %v", record)
+	}
+}
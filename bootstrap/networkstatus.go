@@ -0,0 +1,123 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WaitForNetworkStatus waits until every Multus network our own pod requests appears in its network-status with an IP,
+// watching the pod for up to opts.NetworkStatusTimeout, so its resolver never silently binds the primary interface instead
+// It returns the pod as last seen, or ErrMultusNotReady if the network-status never became ready
+func WaitForNetworkStatus(ctx context.Context, opts Options, pod v1.Pod) (v1.Pod, error) {
+	skip := CheckMultusNetworkStatus(pod)
+	if skip == nil {
+		return pod, nil
+	}
+	if opts.NetworkStatusTimeout <= 0 {
+		return pod, fmt.Errorf("%w: pod %s %s", ErrMultusNotReady, pod.Name, skip.Detail)
+	}
+
+	slog.Info("Waiting for Multus network-status on our own pod", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace,
+		LogKeyReason, skip.Reason, "timeout", opts.NetworkStatusTimeout)
+	waitCtx, cancel := context.WithTimeout(ctx, opts.NetworkStatusTimeout)
+	defer cancel()
+
+	for {
+		updated, ready, err := watchNetworkStatus(waitCtx, opts, pod)
+		pod = updated
+		if ready {
+			slog.Info("Multus network-status is ready", LogKeyPod, pod.Name, LogKeyNamespace, pod.Namespace)
+			return pod, nil
+		}
+		if waitCtx.Err() != nil {
+			break
+		}
+		if err != nil {
+			return pod, err
+		}
+
+		// The watch closed, so catch up with the pod and its resourceVersion before watching again
+		refreshed, err := CurrentPod(waitCtx, opts)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				break
+			}
+			return pod, err
+		}
+		pod = refreshed
+		if CheckMultusNetworkStatus(pod) == nil {
+			return pod, nil
+		}
+	}
+
+	if latest := CheckMultusNetworkStatus(pod); latest != nil {
+		skip = latest
+	}
+	return pod, fmt.Errorf("%w: pod %s %s after waiting %s", ErrMultusNotReady, pod.Name, skip.Detail, opts.NetworkStatusTimeout)
+}
+
+// watchNetworkStatus watches our own pod until its network-status is ready, ctx ends or the watch closes
+func watchNetworkStatus(ctx context.Context, opts Options, pod v1.Pod) (v1.Pod, bool, error) {
+	var watcher watch.Interface
+	err := callWithRetry(ctx, opts, "watch_pod", func(ctx context.Context) error {
+		var err error
+		// The watch outlives a single API call, so it is only bounded by ctx
+		watcher, err = opts.Clientset.CoreV1().Pods(pod.Namespace).Watch(context.WithoutCancel(ctx), metav1.ListOptions{
+			FieldSelector:   fields.OneTermEqualSelector("metadata.name", pod.Name).String(),
+			ResourceVersion: pod.ResourceVersion,
+		})
+		return err
+	})
+	if err != nil {
+		return pod, false, fmt.Errorf("failed to watch current pod %s: %w", pod.Name, err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return pod, false, ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return pod, false, nil
+			}
+			if event.Type == watch.Error {
+				// Usually an expired resourceVersion, so catch up with the pod
+				return pod, false, nil
+			}
+			if event.Type == watch.Deleted {
+				return pod, false, fmt.Errorf("%w: pod %s was deleted while waiting for its network-status", ErrSelfNotFound, pod.Name)
+			}
+			updated, isPod := event.Object.(*v1.Pod)
+			if !isPod || updated.Name != pod.Name {
+				continue
+			}
+			pod = *updated
+			if CheckMultusNetworkStatus(pod) == nil {
+				return pod, true, nil
+			}
+		}
+	}
+}
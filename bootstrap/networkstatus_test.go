@@ -0,0 +1,137 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// networkStatusTestSetup returns a clientset with a peer and our own pod, which requests mynet but has no network-status yet
+func networkStatusTestSetup(t *testing.T) (*fake.Clientset, Options) {
+	peer := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	peer.Namespace = "test-namespace"
+	self := createTestPodWithInvalidMultus("aeron-0", "10.0.0.1", time.Now())
+	self.Namespace = "test-namespace"
+	self.Labels = nil
+
+	opts := testOptions(fake.NewSimpleClientset(&peer, &self))
+	opts.PodName = "aeron-0"
+	opts.BootstrapPath = filepath.Join(t.TempDir(), "bootstrap.properties")
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+	return opts.Clientset.(*fake.Clientset), opts
+}
+
+func TestRunWaitsForNetworkStatus(t *testing.T) {
+	clientset, opts := networkStatusTestSetup(t)
+	opts.NetworkStatusTimeout = 10 * time.Second
+
+	// Multus reports the network shortly after the bootstrap starts waiting
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		pods := clientset.CoreV1().Pods("test-namespace")
+		// An unrelated pod changing must not end the wait
+		peer, _ := pods.Get(context.TODO(), "aeron-1", metav1.GetOptions{})
+		peer.Annotations = map[string]string{"unrelated": "true"}
+		_, _ = pods.Update(context.TODO(), peer, metav1.UpdateOptions{})
+
+		self, _ := pods.Get(context.TODO(), "aeron-0", metav1.GetOptions{})
+		self.Annotations[NetworkStatusAnnotation] = `[{"name":"mynet","interface":"net1","ips":["192.168.1.10"]}]`
+		_, _ = pods.Update(context.TODO(), self, metav1.UpdateOptions{})
+	}()
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.ResolverInterface != "192.168.1.10:8050" {
+		t.Errorf("ResolverInterface = %s, expected the Multus address", result.ResolverInterface)
+	}
+	content, err := os.ReadFile(opts.BootstrapPath)
+	if err != nil || !strings.Contains(string(content), "aeron.driver.resolver.interface=192.168.1.10:8050") {
+		t.Errorf("Bootstrap file = %q (%v), expected the Multus address", content, err)
+	}
+}
+
+func TestWaitForNetworkStatusTimeout(t *testing.T) {
+	_, opts := networkStatusTestSetup(t)
+	opts.NetworkStatusTimeout = 200 * time.Millisecond
+	self, err := CurrentPod(context.TODO(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = WaitForNetworkStatus(context.TODO(), opts, self)
+	if !errors.Is(err, ErrMultusNotReady) {
+		t.Fatalf("WaitForNetworkStatus() error = %v, expected %v", err, ErrMultusNotReady)
+	}
+	if !strings.Contains(err.Error(), "missing "+NetworkStatusAnnotation) || !strings.Contains(err.Error(), "after waiting 200ms") {
+		t.Errorf("Error %q should say what is missing and how long it waited", err)
+	}
+	if elapsed := time.Since(start); elapsed < opts.NetworkStatusTimeout {
+		t.Errorf("Gave up after %v, expected to wait %v", elapsed, opts.NetworkStatusTimeout)
+	}
+}
+
+func TestWaitForNetworkStatusImmediate(t *testing.T) {
+	_, opts := networkStatusTestSetup(t)
+	self, err := CurrentPod(context.TODO(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No Multus networks requested, or already reported, returns straight away
+	ready := createTestPodWithMultus("aeron-0", "10.0.0.1", "mynet", "192.168.1.10", time.Now())
+	if _, err := WaitForNetworkStatus(context.TODO(), opts, ready); err != nil {
+		t.Errorf("WaitForNetworkStatus() with a ready pod error = %v", err)
+	}
+
+	opts.NetworkStatusTimeout = 0
+	start := time.Now()
+	if _, err := WaitForNetworkStatus(context.TODO(), opts, self); !errors.Is(err, ErrMultusNotReady) {
+		t.Errorf("WaitForNetworkStatus() without a timeout error = %v, expected %v", err, ErrMultusNotReady)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Waited %v without a timeout", elapsed)
+	}
+}
+
+func TestWaitForNetworkStatusDeleted(t *testing.T) {
+	clientset, opts := networkStatusTestSetup(t)
+	self, err := CurrentPod(context.TODO(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = clientset.CoreV1().Pods("test-namespace").Delete(context.TODO(), "aeron-0", metav1.DeleteOptions{})
+	}()
+	if _, err := WaitForNetworkStatus(context.TODO(), opts, self); !errors.Is(err, ErrSelfNotFound) {
+		t.Errorf("WaitForNetworkStatus() error = %v, expected %v", err, ErrSelfNotFound)
+	}
+}
@@ -0,0 +1,385 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// Output backends for the rendered bootstrap properties
+const (
+	OutputFile      = "file"
+	OutputConfigMap = "configmap"
+	OutputSecret    = "secret"
+)
+
+const (
+	// PropertiesKey is the ConfigMap or Secret key holding the rendered bootstrap properties
+	PropertiesKey = "bootstrap.properties"
+	// FabricNeighborsKey and FabricMembersKey are the keys of a fabric ConfigMap
+	FabricNeighborsKey = "neighbors"
+	FabricMembersKey   = "members.json"
+
+	// fieldManager is the server-side apply field manager, suffixed per pod so drivers never contend for fields
+	fieldManager = "aeron-k8s-bootstrap"
+	// The API server rejects longer field manager names
+	maxFieldManagerLength = 128
+)
+
+// FabricMember is one media driver in a fabric ConfigMap
+type FabricMember struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace,omitempty"`
+	Endpoint     string `json:"endpoint"`
+	ResolverName string `json:"resolverName"`
+}
+
+// podFieldManager returns the server-side apply field manager used by a pod
+func podFieldManager(podName string) string {
+	manager := fieldManager + "-" + podName
+	if len(manager) > maxFieldManagerLength {
+		manager = manager[:maxFieldManagerLength]
+	}
+	return manager
+}
+
+// outputName returns the name of the ConfigMap or Secret written by our own pod
+func outputName(opts Options) string {
+	if opts.OutputName != "" {
+		return opts.OutputName
+	}
+	return DefaultOutputName(opts.PodName)
+}
+
+// DefaultOutputName returns the name of the ConfigMap or Secret holding a pod's bootstrap properties
+func DefaultOutputName(podName string) string {
+	return podName + "-aeron-bootstrap"
+}
+
+// podOwnerReference makes an object owned by a pod, so it is garbage collected with it
+func podOwnerReference(pod v1.Pod) *metav1ac.OwnerReferenceApplyConfiguration {
+	return metav1ac.OwnerReference().
+		WithAPIVersion("v1").
+		WithKind("Pod").
+		WithName(pod.Name).
+		WithUID(pod.UID)
+}
+
+// applyError wraps a failed apply, distinguishing fields owned by another manager
+func applyError(kind, name string, err error) error {
+	if apierrors.IsConflict(err) {
+		return fmt.Errorf("%w: %s %s: %v", ErrOutputConflict, kind, name, err)
+	}
+	return fmt.Errorf("failed to apply %s %s: %w", kind, name, err)
+}
+
+// outputExists returns whether our pod's configured output has been written before
+func outputExists(ctx context.Context, opts Options, pod v1.Pod) bool {
+	switch opts.Output {
+	case OutputConfigMap:
+		return callWithRetry(ctx, opts, "get_configmap", func(ctx context.Context) error {
+			_, err := opts.Clientset.CoreV1().ConfigMaps(pod.Namespace).Get(ctx, outputName(opts), metav1.GetOptions{})
+			return err
+		}) == nil
+	case OutputSecret:
+		return callWithRetry(ctx, opts, "get_secret", func(ctx context.Context) error {
+			_, err := opts.Clientset.CoreV1().Secrets(pod.Namespace).Get(ctx, outputName(opts), metav1.GetOptions{})
+			return err
+		}) == nil
+	}
+	_, err := os.Stat(opts.BootstrapPath)
+	return err == nil
+}
+
+// ReadOutput returns the bootstrap properties last written to our own pod's configured output, the PropertiesKey of its
+// ConfigMap or Secret or else opts.BootstrapPath, along with where they were read from
+// Output that hasn't been written yet reads as empty properties
+func ReadOutput(ctx context.Context, opts Options) (string, string, error) {
+	name := outputName(opts)
+	switch opts.Output {
+	case OutputConfigMap:
+		var configMap *v1.ConfigMap
+		err := callWithRetry(ctx, opts, "get_configmap", func(ctx context.Context) error {
+			var err error
+			configMap, err = opts.Clientset.CoreV1().ConfigMaps(opts.Namespace).Get(ctx, name, metav1.GetOptions{})
+			return err
+		})
+		location := "ConfigMap " + name
+		if apierrors.IsNotFound(err) {
+			return "", location, nil
+		}
+		if err != nil {
+			return "", location, fmt.Errorf("failed to get ConfigMap %s: %w", name, err)
+		}
+		return configMap.Data[PropertiesKey], location, nil
+	case OutputSecret:
+		var secret *v1.Secret
+		err := callWithRetry(ctx, opts, "get_secret", func(ctx context.Context) error {
+			var err error
+			secret, err = opts.Clientset.CoreV1().Secrets(opts.Namespace).Get(ctx, name, metav1.GetOptions{})
+			return err
+		})
+		location := "Secret " + name
+		if apierrors.IsNotFound(err) {
+			return "", location, nil
+		}
+		if err != nil {
+			return "", location, fmt.Errorf("failed to get Secret %s: %w", name, err)
+		}
+		return string(secret.Data[PropertiesKey]), location, nil
+	}
+
+	existing, err := os.ReadFile(opts.BootstrapPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", opts.BootstrapPath, fmt.Errorf("failed to read bootstrap file: %w", err)
+	}
+	return string(existing), opts.BootstrapPath, nil
+}
+
+// writeOutput writes the rendered properties to the configured output backend, returning false if it was already up to date
+func writeOutput(ctx context.Context, opts Options, plan renderPlan, result Result) (bool, error) {
+	switch opts.Output {
+	case OutputConfigMap:
+		return applyConfigMapOutput(ctx, opts, plan.pod, outputData(opts, result))
+	case OutputSecret:
+		return applySecretOutput(ctx, opts, plan.pod, outputData(opts, result))
+	}
+
+	// The lookup is written first, so clients waiting for the bootstrap file find it in place
+	lookupWritten := false
+	if opts.Lookup != "" {
+		var err error
+		if lookupWritten, err = writeLookupFile(lookupPath(opts), result.Lookup); err != nil {
+			return false, err
+		}
+	}
+
+	// Leave the file untouched if nothing has changed since it was last written
+	bootstrapPath := opts.BootstrapPath
+	if existing, err := os.ReadFile(bootstrapPath); err == nil && string(existing) == result.Properties {
+		slog.Debug("Bootstrap properties unchanged", "path", bootstrapPath)
+		return lookupWritten, nil
+	}
+
+	// Create the bootstrap properties file
+	dir := filepath.Dir(bootstrapPath)
+	if err := createBootstrapPropertiesWithEndpoints(dir, bootstrapPath, plan.neighbors, plan.discoveryPort, result.ResolverName, plan.resolverInterface); err != nil {
+		return false, fmt.Errorf("error creating bootstrap properties file: %w", err)
+	}
+	return true, nil
+}
+
+// outputData returns the keys written to the ConfigMap or Secret output: the properties, and the lookup if enabled
+func outputData(opts Options, result Result) map[string]string {
+	data := map[string]string{PropertiesKey: result.Properties}
+	if opts.Lookup != "" {
+		data[LookupKey(opts.Lookup)] = result.Lookup
+	}
+	return data
+}
+
+// applyConfigMapOutput server-side applies the output data into our pod's ConfigMap, owned by our pod
+func applyConfigMapOutput(ctx context.Context, opts Options, pod v1.Pod, data map[string]string) (bool, error) {
+	return applyOwnedConfigMap(ctx, opts, pod, outputName(opts), podFieldManager(pod.Name), data)
+}
+
+// applyOwnedConfigMap server-side applies data into a ConfigMap owned by pod as the given field manager,
+// in the pod's namespace, returning false without applying if the ConfigMap already holds that data
+func applyOwnedConfigMap(ctx context.Context, opts Options, pod v1.Pod, name, manager string, data map[string]string) (bool, error) {
+	configMaps := opts.Clientset.CoreV1().ConfigMaps(pod.Namespace)
+
+	var existing *v1.ConfigMap
+	err := callWithRetry(ctx, opts, "get_configmap", func(ctx context.Context) error {
+		var err error
+		existing, err = configMaps.Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err == nil && dataUnchanged(existing.Data, data) {
+		slog.Debug("Bootstrap ConfigMap unchanged", "configMap", name)
+		return false, nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get ConfigMap %s: %w", name, err)
+	}
+
+	configMap := corev1ac.ConfigMap(name, pod.Namespace).
+		WithOwnerReferences(podOwnerReference(pod)).
+		WithData(data)
+	applyOptions := metav1.ApplyOptions{FieldManager: manager, Force: opts.ForceConflicts}
+	err = callWithRetry(ctx, opts, "apply_configmap", func(ctx context.Context) error {
+		_, err := configMaps.Apply(ctx, configMap, applyOptions)
+		return err
+	})
+	if err != nil {
+		return false, applyError("ConfigMap", name, err)
+	}
+	slog.Info("Applied bootstrap properties ConfigMap", "configMap", name, LogKeyNamespace, pod.Namespace)
+	return true, nil
+}
+
+// dataUnchanged returns whether existing already holds every key of data with the same value
+func dataUnchanged(existing, data map[string]string) bool {
+	for key, value := range data {
+		if current, ok := existing[key]; !ok || current != value {
+			return false
+		}
+	}
+	return true
+}
+
+// applySecretOutput server-side applies the output data into our pod's Secret, owned by our pod, in the pod's namespace
+func applySecretOutput(ctx context.Context, opts Options, pod v1.Pod, data map[string]string) (bool, error) {
+	name := outputName(opts)
+	secrets := opts.Clientset.CoreV1().Secrets(pod.Namespace)
+
+	var existing *v1.Secret
+	err := callWithRetry(ctx, opts, "get_secret", func(ctx context.Context) error {
+		var err error
+		existing, err = secrets.Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err == nil && dataUnchanged(secretStrings(existing.Data), data) {
+		slog.Debug("Bootstrap Secret unchanged", "secret", name)
+		return false, nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("failed to get Secret %s: %w", name, err)
+	}
+
+	secretData := make(map[string][]byte, len(data))
+	for key, value := range data {
+		secretData[key] = []byte(value)
+	}
+	secret := corev1ac.Secret(name, pod.Namespace).
+		WithOwnerReferences(podOwnerReference(pod)).
+		WithType(v1.SecretTypeOpaque).
+		WithData(secretData)
+	applyOptions := metav1.ApplyOptions{FieldManager: podFieldManager(pod.Name), Force: opts.ForceConflicts}
+	err = callWithRetry(ctx, opts, "apply_secret", func(ctx context.Context) error {
+		_, err := secrets.Apply(ctx, secret, applyOptions)
+		return err
+	})
+	if err != nil {
+		return false, applyError("Secret", name, err)
+	}
+	slog.Info("Applied bootstrap properties Secret", "secret", name, LogKeyNamespace, pod.Namespace)
+	return true, nil
+}
+
+// secretStrings converts Secret data to strings, for comparison with the data to apply
+func secretStrings(data map[string][]byte) map[string]string {
+	strs := make(map[string]string, len(data))
+	for key, value := range data {
+		strs[key] = string(value)
+	}
+	return strs
+}
+
+// FabricData renders the data of a fabric ConfigMap describing every media driver in pods:
+// a comma separated neighbor list, oldest first, and each member's endpoint and resolver name as JSON
+func FabricData(pods []PodInfo) (map[string]string, error) {
+	var endpoints []string
+	for _, pod := range pods {
+		endpoints = append(endpoints, pod.Endpoint())
+	}
+	membersJSON, err := encodeMembers(pods)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		FabricNeighborsKey: strings.Join(endpoints, ","),
+		FabricMembersKey:   membersJSON,
+	}, nil
+}
+
+// encodeMembers encodes pods as a JSON array of FabricMember
+func encodeMembers(pods []PodInfo) (string, error) {
+	members := make([]FabricMember, 0, len(pods))
+	for _, pod := range pods {
+		members = append(members, FabricMember{Name: pod.Name, Namespace: pod.Namespace, Endpoint: pod.Endpoint(), ResolverName: pod.ResolverName})
+	}
+	membersJSON, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode fabric members: %v", err)
+	}
+	return string(membersJSON), nil
+}
+
+// decodeMembers decodes a JSON array of FabricMember back into pods
+func decodeMembers(membersJSON string) ([]PodInfo, error) {
+	var members []FabricMember
+	if err := json.Unmarshal([]byte(membersJSON), &members); err != nil {
+		return nil, fmt.Errorf("failed to decode fabric members: %v", err)
+	}
+	pods := make([]PodInfo, 0, len(members))
+	for _, member := range members {
+		// Endpoints are written with net.JoinHostPort, so an IPv6 address is bracketed
+		host, portStr, err := net.SplitHostPort(member.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q for member %s: %v", member.Endpoint, member.Name, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in endpoint %q for member %s: %v", member.Endpoint, member.Name, err)
+		}
+		pods = append(pods, PodInfo{Name: member.Name, Namespace: member.Namespace, IP: host, Port: port, ResolverName: member.ResolverName})
+	}
+	return pods, nil
+}
+
+// ApplyFabricConfigMap server-side applies a shared ConfigMap describing every media driver in pods,
+// owned by owners, for consumers that need the whole fabric rather than one driver's bootstrap file
+func ApplyFabricConfigMap(ctx context.Context, opts Options, name string, owners []metav1.OwnerReference, pods []PodInfo) error {
+	data, err := FabricData(pods)
+	if err != nil {
+		return err
+	}
+
+	configMap := corev1ac.ConfigMap(name, opts.Namespace).WithData(data)
+	for _, owner := range owners {
+		configMap.WithOwnerReferences(metav1ac.OwnerReference().
+			WithAPIVersion(owner.APIVersion).
+			WithKind(owner.Kind).
+			WithName(owner.Name).
+			WithUID(owner.UID))
+	}
+	applyOptions := metav1.ApplyOptions{FieldManager: fieldManager + "-fabric", Force: opts.ForceConflicts}
+	err = callWithRetry(ctx, opts, "apply_configmap", func(ctx context.Context) error {
+		_, err := opts.Clientset.CoreV1().ConfigMaps(opts.Namespace).Apply(ctx, configMap, applyOptions)
+		return err
+	})
+	if err != nil {
+		return applyError("ConfigMap", name, err)
+	}
+	slog.Info("Applied fabric ConfigMap", "configMap", name, LogKeyNamespace, opts.Namespace, "members", len(pods))
+	return nil
+}
@@ -0,0 +1,223 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// applyAsUpsert makes the fake clientset treat a server-side apply as create-or-replace,
+// as its object tracker only applies patches to objects that already exist
+func applyAsUpsert(clientset *fake.Clientset, resource string, newObject func() runtime.Object) {
+	clientset.PrependReactor("patch", resource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		obj := newObject()
+		if err := json.Unmarshal(patch.GetPatch(), obj); err != nil {
+			return true, nil, err
+		}
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: resource}
+		tracker := clientset.Tracker()
+		if _, err := tracker.Get(gvr, patch.GetNamespace(), patch.GetName()); apierrors.IsNotFound(err) {
+			return true, obj, tracker.Create(gvr, obj, patch.GetNamespace())
+		}
+		return true, obj, tracker.Update(gvr, obj, patch.GetNamespace())
+	})
+}
+
+func outputTestSetup(t *testing.T, output string) (*fake.Clientset, Options) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	self.Namespace = "test-namespace"
+	self.UID = "uid-aeron-0"
+	clientset := fake.NewSimpleClientset(&self)
+	applyAsUpsert(clientset, "configmaps", func() runtime.Object { return &corev1.ConfigMap{} })
+	applyAsUpsert(clientset, "secrets", func() runtime.Object { return &corev1.Secret{} })
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-0"
+	opts.Output = output
+	opts.BootstrapPath = t.TempDir() + "/unused/bootstrap.properties"
+	opts.EmitEvents = false
+	opts.PublishIdentity = false
+	return clientset, opts
+}
+
+func TestRunConfigMapOutput(t *testing.T) {
+	clientset, opts := outputTestSetup(t, OutputConfigMap)
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("First Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps("test-namespace").Get(context.TODO(), "aeron-0-aeron-bootstrap", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected the ConfigMap to be applied: %v", err)
+	}
+	if configMap.Data[PropertiesKey] != result.Properties {
+		t.Errorf("ConfigMap data = %q, expected %q", configMap.Data[PropertiesKey], result.Properties)
+	}
+	if len(configMap.OwnerReferences) != 1 || configMap.OwnerReferences[0].Kind != "Pod" ||
+		configMap.OwnerReferences[0].Name != "aeron-0" || configMap.OwnerReferences[0].UID != "uid-aeron-0" {
+		t.Errorf("ConfigMap owner references = %+v, expected our own pod", configMap.OwnerReferences)
+	}
+
+	result, err = Run(context.TODO(), opts)
+	if err != nil || result.Written {
+		t.Errorf("Unchanged Run() = (%v, %v), expected (false, nil)", result.Written, err)
+	}
+}
+
+func TestRunSecretOutput(t *testing.T) {
+	clientset, opts := outputTestSetup(t, OutputSecret)
+	opts.OutputName = "aeron-bootstrap"
+
+	result, err := Run(context.TODO(), opts)
+	if err != nil || !result.Written {
+		t.Fatalf("Run() = (%v, %v), expected (true, nil)", result.Written, err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets("test-namespace").Get(context.TODO(), "aeron-bootstrap", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected the Secret to be applied: %v", err)
+	}
+	if string(secret.Data[PropertiesKey]) != result.Properties {
+		t.Errorf("Secret data = %q, expected %q", secret.Data[PropertiesKey], result.Properties)
+	}
+}
+
+func TestRunOutputConflict(t *testing.T) {
+	clientset, opts := outputTestSetup(t, OutputConfigMap)
+	clientset.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "aeron-0-aeron-bootstrap",
+			errors.New(`Apply failed with 1 conflict: conflict with "kubectl-edit"`))
+	})
+
+	_, err := Run(context.TODO(), opts)
+	if !errors.Is(err, ErrOutputConflict) {
+		t.Errorf("Run() error = %v, expected %v", err, ErrOutputConflict)
+	}
+}
+
+func TestPodFieldManager(t *testing.T) {
+	if got := podFieldManager("aeron-0"); got != "aeron-k8s-bootstrap-aeron-0" {
+		t.Errorf("podFieldManager() = %s, expected aeron-k8s-bootstrap-aeron-0", got)
+	}
+	if got := podFieldManager(strings.Repeat("a", 253)); len(got) != maxFieldManagerLength {
+		t.Errorf("podFieldManager() length = %d, expected %d", len(got), maxFieldManagerLength)
+	}
+}
+
+func TestApplyFabricConfigMap(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	applyAsUpsert(clientset, "configmaps", func() runtime.Object { return &corev1.ConfigMap{} })
+	opts := testOptions(clientset)
+
+	pods := []PodInfo{
+		{Name: "aeron-0", IP: "10.0.0.1", Port: 8050, ResolverName: "aeron-0.test-namespace.aeron"},
+		{Name: "aeron-1", IP: "10.0.0.2", Port: 8051, ResolverName: "aeron-1.test-namespace.aeron"},
+	}
+	owners := []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "aeron-controller", UID: "uid-controller"}}
+	if err := ApplyFabricConfigMap(context.TODO(), opts, "aeron-fabric", owners, pods); err != nil {
+		t.Fatalf("ApplyFabricConfigMap() error = %v", err)
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps("test-namespace").Get(context.TODO(), "aeron-fabric", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected the fabric ConfigMap to be applied: %v", err)
+	}
+	if configMap.Data[FabricNeighborsKey] != "10.0.0.1:8050,10.0.0.2:8051" {
+		t.Errorf("Fabric neighbors = %q, expected 10.0.0.1:8050,10.0.0.2:8051", configMap.Data[FabricNeighborsKey])
+	}
+	var members []FabricMember
+	if err := json.Unmarshal([]byte(configMap.Data[FabricMembersKey]), &members); err != nil {
+		t.Fatalf("Fabric members are not valid JSON: %v", err)
+	}
+	if len(members) != 2 || members[1] != (FabricMember{Name: "aeron-1", Endpoint: "10.0.0.2:8051", ResolverName: "aeron-1.test-namespace.aeron"}) {
+		t.Errorf("Fabric members = %+v", members)
+	}
+	if len(configMap.OwnerReferences) != 1 || configMap.OwnerReferences[0].Name != "aeron-controller" {
+		t.Errorf("Fabric owner references = %+v, expected the controller Deployment", configMap.OwnerReferences)
+	}
+}
+
+func TestDecodeMembersRoundTrip(t *testing.T) {
+	pods := []PodInfo{
+		{Name: "aeron-0", Namespace: "test-namespace", IP: "10.0.0.1", Port: 8050, ResolverName: "aeron-0.test-namespace.aeron"},
+		{Name: "aeron-1", Namespace: "test-namespace", IP: "fd00::2", Port: 8051, ResolverName: "aeron-1.test-namespace.aeron"},
+	}
+	membersJSON, err := encodeMembers(pods)
+	if err != nil {
+		t.Fatalf("encodeMembers() error = %v", err)
+	}
+	if !strings.Contains(membersJSON, `"[fd00::2]:8051"`) {
+		t.Errorf("Expected the IPv6 endpoint to be bracketed, got %s", membersJSON)
+	}
+	decoded, err := decodeMembers(membersJSON)
+	if err != nil {
+		t.Fatalf("decodeMembers() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, pods) {
+		t.Errorf("decodeMembers() = %+v, expected %+v", decoded, pods)
+	}
+
+	if _, err := decodeMembers(`[{"name":"aeron-0","endpoint":"fd00::2"}]`); err == nil {
+		t.Errorf("Expected an endpoint without a port to be rejected")
+	}
+}
+
+func TestReadOutput(t *testing.T) {
+	for _, output := range []string{OutputFile, OutputConfigMap, OutputSecret} {
+		t.Run(output, func(t *testing.T) {
+			_, opts := outputTestSetup(t, output)
+
+			existing, _, err := ReadOutput(context.TODO(), opts)
+			if err != nil || existing != "" {
+				t.Fatalf("ReadOutput() before Run = (%q, %v), expected empty properties", existing, err)
+			}
+
+			result, err := Run(context.TODO(), opts)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			existing, location, err := ReadOutput(context.TODO(), opts)
+			if err != nil || existing != result.Properties {
+				t.Errorf("ReadOutput() = (%q, %v), expected the properties written by Run, %q", existing, err, result.Properties)
+			}
+			if output != OutputFile && !strings.HasSuffix(location, " aeron-0-aeron-bootstrap") {
+				t.Errorf("ReadOutput() location = %s, expected aeron-0-aeron-bootstrap", location)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// PodIndexLabel is set by Kubernetes on StatefulSet pods to their ordinal
+const PodIndexLabel = "apps.kubernetes.io/pod-index"
+
+// MaxResolverNameLength is the longest resolver name accepted from a name template
+// Names are used as hostnames in channel endpoints, so are held to DNS subdomain rules
+const MaxResolverNameLength = validation.DNS1123SubdomainMaxLength
+
+// SkipReasonInvalidResolverName is the reason a candidate pod is skipped when its name template renders an invalid name
+const SkipReasonInvalidResolverName = "InvalidResolverName"
+
+// ResolverNameData is what a resolver name template is executed against, e.g.
+// "{{.StatefulSet}}-{{.Ordinal}}.{{.Cluster}}{{.Suffix}}" or "{{index .Labels \"app\"}}.{{index .Labels \"zone\"}}"
+type ResolverNameData struct {
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+	// StatefulSet and Ordinal are empty unless the pod belongs to a StatefulSet
+	StatefulSet string
+	Ordinal     string
+	// Node is empty until the pod is scheduled
+	Node    string
+	Cluster string
+	Suffix  string
+}
+
+// resolverNameFuncs are the functions available to resolver name templates, besides the text/template builtins
+var resolverNameFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"replace": func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+}
+
+// ParseResolverNameTemplate parses a resolver name template, checking it only refers to ResolverNameData fields
+// Missing label or annotation keys fail when the template is executed
+func ParseResolverNameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("resolver-name").Funcs(resolverNameFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resolver name template: %w", err)
+	}
+	// A sample pod catches references to fields that don't exist, which text/template only reports on execution
+	sample := ResolverNameData{Name: "aeron-0", Namespace: "default", Labels: map[string]string{}, Annotations: map[string]string{}}
+	if err := template.Must(tmpl.Clone()).Option("missingkey=zero").Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, fmt.Errorf("invalid resolver name template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// ValidateResolverName checks a resolver name is a DNS subdomain of at most MaxResolverNameLength characters,
+// each dot-separated part a DNS label
+func ValidateResolverName(name string) error {
+	if len(name) > MaxResolverNameLength {
+		return fmt.Errorf("resolver name %q is longer than %d characters", name, MaxResolverNameLength)
+	}
+	for _, part := range strings.Split(name, ".") {
+		if errs := validation.IsDNS1123Label(part); len(errs) > 0 {
+			return fmt.Errorf("invalid resolver name %q: %s", name, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// statefulSetOrdinal returns the StatefulSet controlling a pod and the pod's ordinal in it, from the
+// apps.kubernetes.io/pod-index label or its name's suffix, or false if it isn't a StatefulSet pod
+func statefulSetOrdinal(pod v1.Pod) (string, int, bool) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil || owner.Kind != "StatefulSet" {
+		return "", 0, false
+	}
+	index, ok := pod.Labels[PodIndexLabel]
+	if !ok {
+		index, ok = strings.CutPrefix(pod.Name, owner.Name+"-")
+	}
+	ordinal, err := strconv.Atoi(index)
+	if !ok || err != nil || ordinal < 0 {
+		return "", 0, false
+	}
+	return owner.Name, ordinal, true
+}
+
+// resolverNameData returns what opts.ResolverNameTemplate is executed against for a pod named podName in namespace
+func resolverNameData(pod v1.Pod, podName, namespace string, opts Options) ResolverNameData {
+	data := ResolverNameData{
+		Name:        podName,
+		Namespace:   namespace,
+		Labels:      pod.Labels,
+		Annotations: pod.Annotations,
+		Node:        pod.Spec.NodeName,
+		Cluster:     opts.ClusterName,
+		Suffix:      opts.HostnameSuffix,
+	}
+	if statefulSet, ordinal, ok := statefulSetOrdinal(pod); ok {
+		data.StatefulSet, data.Ordinal = statefulSet, strconv.Itoa(ordinal)
+	}
+	return data
+}
+
+// podResolverName returns the resolver name a pod advertises via the aeron.io/resolver-name annotation,
+// held to the same rules as a templated name, or otherwise the one defaultResolverName gives it
+func podResolverName(pod v1.Pod, podName, namespace string, opts Options) (string, error) {
+	if name := getPodResolverName(pod, ""); name != "" {
+		if err := ValidateResolverName(name); err != nil {
+			return "", fmt.Errorf("invalid %s annotation: %w", ResolverNameAnnotation, err)
+		}
+		return name, nil
+	}
+	return defaultResolverName(pod, podName, namespace, opts)
+}
+
+// defaultResolverName returns the resolver name of a pod that doesn't advertise its own: opts.ResolverNameTemplate if set,
+// otherwise podName.namespace followed by opts.HostnameSuffix, or its Node's name instead if it uses host networking and node naming
+func defaultResolverName(pod v1.Pod, podName, namespace string, opts Options) (string, error) {
+	if opts.ResolverNameTemplate != nil {
+		var name bytes.Buffer
+		if err := opts.ResolverNameTemplate.Execute(&name, resolverNameData(pod, podName, namespace, opts)); err != nil {
+			return "", fmt.Errorf("failed to render resolver name: %w", err)
+		}
+		return name.String(), ValidateResolverName(name.String())
+	}
+	if pod.Spec.HostNetwork && opts.HostNetwork.Naming == HostNetworkNamingNode && pod.Spec.NodeName != "" {
+		return pod.Spec.NodeName + opts.HostnameSuffix, nil
+	}
+	return buildAeronHostname(podName, namespace, opts.HostnameSuffix), nil
+}
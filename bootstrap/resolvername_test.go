@@ -0,0 +1,195 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// createStatefulSetPod returns a media driver pod controlled by the named StatefulSet, with the given pod-index label
+func createStatefulSetPod(statefulSet string, ordinal int, podIndex string, creationTime time.Time) corev1.Pod {
+	pod := createTestPod(fmt.Sprintf("%s-%d", statefulSet, ordinal), fmt.Sprintf("10.0.0.%d", ordinal+1), "Running", creationTime)
+	pod.Namespace = "test-namespace"
+	if podIndex != "" {
+		pod.Labels[PodIndexLabel] = podIndex
+	}
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: statefulSet, Controller: &controller}}
+	return pod
+}
+
+func TestDefaultResolverName(t *testing.T) {
+	statefulSetPod := createStatefulSetPod("aeron", 2, "2", time.Now())
+	statefulSetPod.Spec.NodeName = "worker-1"
+	labelled := createTestPod("driver-abc12", "10.0.0.1", "Running", time.Now())
+	labelled.Namespace = "test-namespace"
+	labelled.Labels["app"] = "pricing"
+	labelled.Labels["zone"] = "EU_West_1"
+
+	tests := []struct {
+		name        string
+		pod         corev1.Pod
+		template    string
+		expected    string
+		expectError string
+	}{
+		{name: "default", pod: statefulSetPod, expected: "aeron-2.test-namespace.aeron"},
+		{
+			name:     "statefulset ordinal and cluster",
+			pod:      statefulSetPod,
+			template: "{{.StatefulSet}}-{{.Ordinal}}.{{.Cluster}}.prod{{.Suffix}}",
+			expected: "aeron-2.london.prod.aeron",
+		},
+		{name: "node", pod: statefulSetPod, template: "{{.Node}}.{{.Namespace}}", expected: "worker-1.test-namespace"},
+		{
+			name:     "labels",
+			pod:      labelled,
+			template: `{{index .Labels "app"}}.{{index .Labels "zone" | lower | replace "_" "-"}}`,
+			expected: "pricing.eu-west-1",
+		},
+		{name: "missing label", pod: labelled, template: `{{.Labels.tier}}.aeron`, expectError: "map has no entry"},
+		{name: "no ordinal outside a statefulset", pod: labelled, template: "{{.Name}}-{{.Ordinal}}.aeron", expectError: "invalid resolver name"},
+		{name: "invalid characters", pod: labelled, template: `{{index .Labels "zone"}}.aeron`, expectError: "invalid resolver name"},
+		{name: "too long", pod: labelled, template: strings.Repeat("{{.Name}}.", 30) + "aeron", expectError: "longer than 253"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.ResolverNameTemplate = mustParseResolverNameTemplate(t, tt.template)
+			opts.ClusterName = "london"
+			name, err := defaultResolverName(tt.pod, tt.pod.Name, tt.pod.Namespace, opts)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("defaultResolverName() error = %v, expected %q", err, tt.expectError)
+				}
+				return
+			}
+			if err != nil || name != tt.expected {
+				t.Errorf("defaultResolverName() = %q, %v, expected %q", name, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatefulSetOrdinal(t *testing.T) {
+	tests := []struct {
+		name            string
+		pod             corev1.Pod
+		expectedSet     string
+		expectedOrdinal int
+		expectedOK      bool
+	}{
+		{name: "pod-index label", pod: createStatefulSetPod("aeron", 3, "3", time.Now()), expectedSet: "aeron", expectedOrdinal: 3, expectedOK: true},
+		{name: "name suffix without the label", pod: createStatefulSetPod("aeron", 1, "", time.Now()), expectedSet: "aeron", expectedOrdinal: 1, expectedOK: true},
+		{name: "invalid pod-index label", pod: createStatefulSetPod("aeron", 1, "one", time.Now())},
+		{name: "not owned by a statefulset", pod: createTestPod("aeron-1", "10.0.0.1", "Running", time.Now())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statefulSet, ordinal, ok := statefulSetOrdinal(tt.pod)
+			if statefulSet != tt.expectedSet || ordinal != tt.expectedOrdinal || ok != tt.expectedOK {
+				t.Errorf("statefulSetOrdinal() = (%s, %d, %v), expected (%s, %d, %v)", statefulSet, ordinal, ok,
+					tt.expectedSet, tt.expectedOrdinal, tt.expectedOK)
+			}
+		})
+	}
+}
+
+func TestPodResolverNameValidatesAnnotation(t *testing.T) {
+	pod := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now())
+	pod.Namespace = "test-namespace"
+	opts := DefaultOptions()
+
+	pod.Annotations = map[string]string{ResolverNameAnnotation: "aeron-0.london.aeron"}
+	if name, err := podResolverName(pod, pod.Name, pod.Namespace, opts); err != nil || name != "aeron-0.london.aeron" {
+		t.Errorf("podResolverName() = %q, %v, expected the annotated name", name, err)
+	}
+
+	pod.Annotations = map[string]string{ResolverNameAnnotation: "Aeron_0.london"}
+	if _, err := podResolverName(pod, pod.Name, pod.Namespace, opts); err == nil || !strings.Contains(err.Error(), ResolverNameAnnotation) {
+		t.Errorf("podResolverName() error = %v, expected the invalid annotation to be rejected", err)
+	}
+}
+
+func TestParseResolverNameTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{"{{.Name}}.{{.Namespace}}{{.Suffix}}", true},
+		{`{{index .Annotations "example.com/alias"}}.aeron`, true},
+		{"{{.Name", false},
+		{"{{.PodName}}.aeron", false},
+		{"{{upper .Name}}.aeron", false},
+	}
+
+	for _, tt := range tests {
+		if _, err := ParseResolverNameTemplate(tt.template); (err == nil) != tt.valid {
+			t.Errorf("ParseResolverNameTemplate(%q) error = %v, expected valid %v", tt.template, err, tt.valid)
+		}
+	}
+}
+
+func TestDiscoverPodsSkipsInvalidResolverName(t *testing.T) {
+	// A pod missing the label the template needs is skipped, a published name is used as is
+	zoned := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now().Add(-10*time.Minute))
+	zoned.Namespace = "test-namespace"
+	zoned.Labels["zone"] = "eu-west-1"
+	unzoned := createTestPod("aeron-1", "10.0.0.2", "Running", time.Now().Add(-5*time.Minute))
+	unzoned.Namespace = "test-namespace"
+	published := createTestPod("aeron-2", "10.0.0.3", "Running", time.Now().Add(-1*time.Minute))
+	published.Namespace = "test-namespace"
+	published.Annotations = map[string]string{PublishedNameAnnotation: "aeron-2.published.aeron"}
+	clientset := fake.NewSimpleClientset(&zoned, &unzoned, &published)
+
+	opts := testOptions(clientset)
+	opts.ResolverNameTemplate = mustParseResolverNameTemplate(t, "{{.Name}}.{{.Labels.zone}}{{.Suffix}}")
+	pods, skipped, err := DiscoverPods(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("DiscoverPods() error = %v", err)
+	}
+	if len(pods) != 2 || pods[0].ResolverName != "aeron-0.eu-west-1.aeron" || pods[1].ResolverName != "aeron-2.published.aeron" {
+		t.Errorf("DiscoverPods() = %v, expected aeron-0 named by the template and aeron-2 by its annotation", pods)
+	}
+	if len(skipped) != 1 || skipped[0].Name != "aeron-1" || skipped[0].Reason != SkipReasonInvalidResolverName {
+		t.Errorf("DiscoverPods() skipped = %v, expected aeron-1 with %s", skipped, SkipReasonInvalidResolverName)
+	}
+}
+
+// mustParseResolverNameTemplate parses a resolver name template for Options, an empty one meaning none
+func mustParseResolverNameTemplate(t *testing.T, text string) *template.Template {
+	t.Helper()
+	if text == "" {
+		return nil
+	}
+	tmpl, err := ParseResolverNameTemplate(text)
+	if err != nil {
+		t.Fatalf("ParseResolverNameTemplate(%q) error = %v", text, err)
+	}
+	return tmpl
+}
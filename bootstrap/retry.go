@@ -0,0 +1,112 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// apiBackoff is the retry schedule for transient Kubernetes API errors
+// Steps is the maximum number of retries, the operation deadline may end retrying sooner
+var apiBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.5,
+	Steps:    6,
+	Cap:      15 * time.Second,
+}
+
+// isTransientAPIError returns whether a Kubernetes API error is worth retrying:
+// throttling, server-side errors, timeouts and dropped or refused connections
+func isTransientAPIError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsUnexpectedServerError(err) {
+		return true
+	}
+	var statusErr apierrors.APIStatus
+	if errors.As(err, &statusErr) && statusErr.Status().Code >= 500 {
+		return true
+	}
+
+	if utilnet.IsConnectionRefused(err) || utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// A per-call timeout expiring, while the overall deadline has not
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// callWithRetry calls fn with a per-call timeout of opts.APITimeout, retrying transient errors with exponential backoff and jitter
+// until it succeeds, fails permanently, runs out of retries or ctx is done
+func callWithRetry(ctx context.Context, opts Options, operation string, fn func(ctx context.Context) error) error {
+	backoff := apiBackoff
+	timeout := opts.APITimeout
+	if timeout <= 0 {
+		timeout = DefaultAPITimeout
+	}
+
+	for attempt := 1; ; attempt++ {
+		callCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := fn(callCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		opts.Metrics.observeAPIError(operation)
+
+		if !isTransientAPIError(err) {
+			return err
+		}
+		if ctx.Err() != nil || backoff.Steps < 1 {
+			return fmt.Errorf("%w: %s: %w", ErrAPIUnavailable, operation, err)
+		}
+
+		delay := backoff.Step()
+		slog.Warn("Transient Kubernetes API error, retrying", "operation", operation, "attempt", attempt, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %s: %w", ErrAPIUnavailable, operation, err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var podsResource = schema.GroupResource{Resource: "pods"}
+
+// useFastBackoff shortens the retry schedule for the duration of a test
+func useFastBackoff(t *testing.T, steps int) {
+	previous := apiBackoff
+	apiBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 2.0, Jitter: 0.5, Steps: steps, Cap: 5 * time.Millisecond}
+	t.Cleanup(func() { apiBackoff = previous })
+}
+
+// failingReactor fails the first failures calls with err, then lets the fake clientset handle them
+func failingReactor(failures int, err error, calls *int) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		*calls++
+		if *calls <= failures {
+			return true, nil, err
+		}
+		return false, nil, nil
+	}
+}
+
+func TestDiscoverPodsRetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable("etcd leader changed")},
+		{name: "too many requests", err: apierrors.NewTooManyRequests("slow down", 1)},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("boom"))},
+		{name: "connection refused", err: fmt.Errorf("dial tcp 10.96.0.1:443: %w", syscall.ECONNREFUSED)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useFastBackoff(t, 5)
+
+			clientset := fake.NewSimpleClientset()
+			pod := createTestPod("aeron-1", "10.0.0.1", "Running", time.Now())
+			pod.Namespace = "test-namespace"
+			if err := clientset.Tracker().Add(&pod); err != nil {
+				t.Fatalf("Failed to add test pod: %v", err)
+			}
+
+			calls := 0
+			clientset.PrependReactor("list", "pods", failingReactor(2, tt.err, &calls))
+
+			result, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
+			if err != nil {
+				t.Fatalf("DiscoverPods() error = %v", err)
+			}
+			if len(result) != 1 {
+				t.Errorf("DiscoverPods() returned %d pods, expected 1", len(result))
+			}
+			if calls != 3 {
+				t.Errorf("Expected 3 list calls (2 failures then success), got %d", calls)
+			}
+		})
+	}
+}
+
+func TestDiscoverPodsDoesNotRetryPermanentErrors(t *testing.T) {
+	useFastBackoff(t, 5)
+
+	clientset := fake.NewSimpleClientset()
+	calls := 0
+	clientset.PrependReactor("list", "pods", failingReactor(10, apierrors.NewForbidden(podsResource, "", errors.New("rbac")), &calls))
+
+	_, err := discoverTestPods(clientset, "aeron.io/media-driver=true", 0)
+	if err == nil {
+		t.Fatalf("Expected a forbidden error")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 list call for a permanent error, got %d", calls)
+	}
+}
+
+func TestCallWithRetryGivesUpAfterMaxRetries(t *testing.T) {
+	useFastBackoff(t, 3)
+
+	calls := 0
+	err := callWithRetry(context.TODO(), Options{}, "test", func(ctx context.Context) error {
+		calls++
+		return apierrors.NewServiceUnavailable("down")
+	})
+	if !apierrors.IsServiceUnavailable(err) {
+		t.Errorf("callWithRetry() error = %v, expected service unavailable", err)
+	}
+	if calls != 4 {
+		t.Errorf("Expected 4 calls (1 attempt plus 3 retries), got %d", calls)
+	}
+}
+
+func TestCallWithRetryStopsAtDeadline(t *testing.T) {
+	previous := apiBackoff
+	apiBackoff = wait.Backoff{Duration: time.Hour, Factor: 1.0, Steps: 5}
+	defer func() { apiBackoff = previous }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	calls := 0
+	err := callWithRetry(ctx, Options{}, "test", func(ctx context.Context) error {
+		calls++
+		return apierrors.NewTooManyRequests("slow down", 1)
+	})
+	if err == nil {
+		t.Fatalf("Expected an error once the deadline passed")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call before the deadline, got %d", calls)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected callWithRetry to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestCallWithRetryAppliesPerCallTimeout(t *testing.T) {
+	useFastBackoff(t, 2)
+	calls := 0
+	err := callWithRetry(context.Background(), Options{APITimeout: 10 * time.Millisecond}, "test", func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			// Simulate a hung API server on the first call
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("callWithRetry() error = %v, expected the retry to succeed", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+}
+
+func TestCurrentPodRetriesAndReturnsErrors(t *testing.T) {
+	useFastBackoff(t, 5)
+
+	clientset := fake.NewSimpleClientset()
+	pod := createTestPod("aeron-1", "10.0.0.1", "Running", time.Now())
+	pod.Namespace = "test-namespace"
+	if err := clientset.Tracker().Add(&pod); err != nil {
+		t.Fatalf("Failed to add test pod: %v", err)
+	}
+
+	calls := 0
+	clientset.PrependReactor("get", "pods", failingReactor(1, apierrors.NewServerTimeout(podsResource, "get", 1), &calls))
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-1"
+	result, err := CurrentPod(context.TODO(), opts)
+	if err != nil || result.Name != "aeron-1" {
+		t.Errorf("CurrentPod() = (%s, %v), expected (aeron-1, nil)", result.Name, err)
+	}
+
+	// A missing pod is an error returned to the caller, not a fatal exit
+	opts.Namespace = "other-namespace"
+	_, err = CurrentPod(context.TODO(), opts)
+	if err == nil {
+		t.Errorf("Expected an error for a missing pod")
+	}
+}
+
+func TestIsTransientAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "nil", err: nil, expected: false},
+		{name: "too many requests", err: apierrors.NewTooManyRequests("", 1), expected: true},
+		{name: "service unavailable", err: apierrors.NewServiceUnavailable(""), expected: true},
+		{name: "server timeout", err: apierrors.NewServerTimeout(podsResource, "list", 1), expected: true},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("")), expected: true},
+		{name: "bad gateway", err: apierrors.NewGenericServerResponse(502, "list", podsResource, "", "", 0, false), expected: true},
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), expected: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), expected: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, expected: true},
+		{name: "per-call timeout", err: context.DeadlineExceeded, expected: true},
+		{name: "not found", err: apierrors.NewNotFound(podsResource, "aeron-1"), expected: false},
+		{name: "forbidden", err: apierrors.NewForbidden(podsResource, "", errors.New("")), expected: false},
+		{name: "unauthorized", err: apierrors.NewUnauthorized(""), expected: false},
+		{name: "cancelled", err: context.Canceled, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := isTransientAPIError(tt.err); result != tt.expected {
+				t.Errorf("isTransientAPIError(%v) = %v, expected %v", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Ordinal topologies, bootstrapping StatefulSet members against each other by ordinal instead of by age
+const (
+	// OrdinalPredecessors points member N at members 0 to N-1, and member 0 at itself
+	OrdinalPredecessors = "predecessors"
+	// OrdinalFirst points every member at member 0
+	OrdinalFirst = "first"
+)
+
+// DefaultClusterDomain is the cluster DNS domain of the StatefulSet member names predicted for members without an address
+const DefaultClusterDomain = "cluster.local"
+
+// IPSourceStatefulSetDNS is the IPSource of a member without an address yet, reached at its stable DNS name
+const IPSourceStatefulSetDNS = "StatefulSetDNS"
+
+// ValidateOrdinal checks an ordinal topology is known, empty meaning neighbors are chosen by age
+func ValidateOrdinal(topology string) error {
+	switch topology {
+	case "", OrdinalPredecessors, OrdinalFirst:
+		return nil
+	}
+	return fmt.Errorf("invalid ordinal topology %q, expected %s or %s", topology, OrdinalPredecessors, OrdinalFirst)
+}
+
+// ordinalNeighbors returns the ordinals a member bootstraps against in the given topology, lowest first,
+// for a StatefulSet whose ordinals begin at start
+func ordinalNeighbors(topology string, start, ordinal int) []int {
+	if topology == OrdinalFirst || ordinal <= start {
+		return []int{start}
+	}
+	ordinals := make([]int, 0, ordinal-start)
+	for i := start; i < ordinal; i++ {
+		ordinals = append(ordinals, i)
+	}
+	return ordinals
+}
+
+// DiscoverOrdinalPeers finds the members of our own pod's StatefulSet it bootstraps against in the opts.Ordinal topology,
+// ordered by ordinal and limited to opts.MaxPods, additionally returning the members skipped by validation
+// Members not yet created, or without an address yet, are predicted from the StatefulSet's stable DNS names
+func DiscoverOrdinalPeers(ctx context.Context, opts Options, currentPod v1.Pod) ([]PodInfo, []PodSkip, error) {
+	name, ordinal, ok := statefulSetOrdinal(currentPod)
+	if !ok {
+		return nil, nil, fmt.Errorf("pod %s is not a StatefulSet member with an ordinal, needed by ordinal topology %s", currentPod.Name, opts.Ordinal)
+	}
+
+	var statefulSet *appsv1.StatefulSet
+	err := callWithRetry(ctx, opts, "get_statefulset", func(ctx context.Context) error {
+		var err error
+		statefulSet, err = opts.Clientset.AppsV1().StatefulSets(opts.Namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get StatefulSet %s: %w", name, err)
+	}
+	start := 0
+	if statefulSet.Spec.Ordinals != nil {
+		start = int(statefulSet.Spec.Ordinals.Start)
+	}
+	wanted := ordinalNeighbors(opts.Ordinal, start, ordinal)
+	if opts.MaxPods > 0 && len(wanted) > opts.MaxPods {
+		wanted = wanted[:opts.MaxPods]
+	}
+
+	pods, err := listCandidatePods(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	members := make(map[int]PodInfo, len(wanted))
+	skippedOrdinals := make(map[int]bool)
+	var skipped []PodSkip
+	nodes := nodeCache{}
+	for _, pod := range pods {
+		podStatefulSet, podOrdinal, ok := statefulSetOrdinal(pod)
+		if !ok || podStatefulSet != name {
+			continue
+		}
+		podInfo, skip, err := evaluatePod(ctx, pod, opts, nodes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if skip != nil {
+			logSkip(pod, skip)
+			skipped = append(skipped, *skip)
+			skippedOrdinals[podOrdinal] = true
+			continue
+		}
+		if podInfo.IP != "" {
+			members[podOrdinal] = podInfo
+		}
+	}
+
+	var neighbors []PodInfo
+	for _, neighborOrdinal := range wanted {
+		if skippedOrdinals[neighborOrdinal] {
+			continue
+		}
+		member, found := members[neighborOrdinal]
+		if !found {
+			if member, found, err = predictMember(statefulSet, neighborOrdinal, opts); err != nil {
+				return nil, nil, err
+			}
+		}
+		if !found {
+			continue
+		}
+		slog.Info("Selected bootstrap neighbor", LogKeyPod, member.Name, LogKeyNamespace, opts.Namespace, LogKeyIP, member.IP,
+			"endpoint", member.Endpoint(), "ordinal", neighborOrdinal, "source", member.IPSource)
+		neighbors = append(neighbors, member)
+	}
+	if len(neighbors) == 0 {
+		slog.Warn("No StatefulSet members found to bootstrap against", LogKeyNamespace, opts.Namespace, "statefulSet", name, "ordinal", ordinal)
+	}
+	return neighbors, skipped, nil
+}
+
+// predictMember returns the StatefulSet member with the given ordinal as reached at its stable DNS name,
+// <statefulset>-<ordinal>.<service>.<namespace>.svc.<cluster domain>, or false if the StatefulSet has no governing Service
+func predictMember(statefulSet *appsv1.StatefulSet, ordinal int, opts Options) (PodInfo, bool, error) {
+	if statefulSet.Spec.ServiceName == "" {
+		slog.Warn("StatefulSet has no serviceName, cannot predict members without an address", "statefulSet", statefulSet.Name, "ordinal", ordinal)
+		return PodInfo{}, false, nil
+	}
+	clusterDomain := opts.ClusterDomain
+	if clusterDomain == "" {
+		clusterDomain = DefaultClusterDomain
+	}
+
+	// The member as it will be created, for naming it and finding its port like its peers will
+	controller := true
+	pod := v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%d", statefulSet.Name, ordinal),
+			Namespace:   opts.Namespace,
+			Labels:      maps.Clone(statefulSet.Spec.Template.Labels),
+			Annotations: maps.Clone(statefulSet.Spec.Template.Annotations),
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "StatefulSet", Name: statefulSet.Name, UID: statefulSet.UID, Controller: &controller,
+			}},
+		},
+	}
+	if pod.Labels == nil {
+		pod.Labels = map[string]string{}
+	}
+	pod.Labels[PodIndexLabel] = strconv.Itoa(ordinal)
+	resolverName, err := podResolverName(pod, pod.Name, pod.Namespace, opts)
+	if err != nil {
+		return PodInfo{}, false, fmt.Errorf("failed to name predicted StatefulSet member %s: %w", pod.Name, err)
+	}
+
+	return PodInfo{
+		Name:         pod.Name,
+		Namespace:    pod.Namespace,
+		IP:           fmt.Sprintf("%s.%s.%s.svc.%s", pod.Name, statefulSet.Spec.ServiceName, opts.Namespace, clusterDomain),
+		Port:         getPodResolverPort(pod, opts.DiscoveryPort),
+		ResolverName: resolverName,
+		IPSource:     IPSourceStatefulSetDNS,
+	}, true, nil
+}
@@ -0,0 +1,218 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// createTestStatefulSet returns the StatefulSet "aeron" governed by the given Service, its ordinals beginning at start
+func createTestStatefulSet(serviceName string, start int32) *appsv1.StatefulSet {
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "aeron", Namespace: "test-namespace"},
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: serviceName,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"aeron.io/media-driver": "true", "zone": "eu-west-1"}},
+			},
+		},
+	}
+	if start > 0 {
+		statefulSet.Spec.Ordinals = &appsv1.StatefulSetOrdinals{Start: start}
+	}
+	return statefulSet
+}
+
+func TestDiscoverOrdinalPeers(t *testing.T) {
+	tests := []struct {
+		name         string
+		topology     string
+		ordinal      int
+		existing     []int
+		maxPods      int
+		serviceName  string
+		start        int32
+		template     string
+		annotations  map[string]string
+		expected     []string
+		expectedName string
+	}{
+		{
+			name:        "predecessors, predicting a missing member",
+			topology:    OrdinalPredecessors,
+			ordinal:     3,
+			existing:    []int{3, 1, 0},
+			serviceName: "aeron-headless",
+			expected:    []string{"10.0.0.1:8050", "10.0.0.2:8050", "aeron-2.aeron-headless.test-namespace.svc.cluster.local:8050"},
+		},
+		{
+			name:        "member 0 bootstraps against itself",
+			topology:    OrdinalPredecessors,
+			ordinal:     0,
+			existing:    []int{0, 1},
+			serviceName: "aeron-headless",
+			expected:    []string{"10.0.0.1:8050"},
+		},
+		{
+			name:         "first, predicting member 0",
+			topology:     OrdinalFirst,
+			ordinal:      2,
+			existing:     []int{2, 1},
+			serviceName:  "aeron-headless",
+			template:     `{{.StatefulSet}}-{{.Ordinal}}.{{index .Labels "zone"}}{{.Suffix}}`,
+			expected:     []string{"aeron-0.aeron-headless.test-namespace.svc.cluster.local:8050"},
+			expectedName: "aeron-0.eu-west-1.aeron",
+		},
+		{
+			name:         "predicting a member annotated by the pod template",
+			topology:     OrdinalFirst,
+			ordinal:      1,
+			existing:     []int{1},
+			serviceName:  "aeron-headless",
+			template:     `{{.StatefulSet}}-{{.Ordinal}}{{.Suffix}}`,
+			annotations:  map[string]string{ResolverPortAnnotation: "9050", ResolverNameAnnotation: "aeron-first"},
+			expected:     []string{"aeron-0.aeron-headless.test-namespace.svc.cluster.local:9050"},
+			expectedName: "aeron-first",
+		},
+		{
+			name:        "limited to the lowest ordinals",
+			topology:    OrdinalPredecessors,
+			ordinal:     3,
+			existing:    []int{0, 1, 2, 3},
+			maxPods:     2,
+			serviceName: "aeron-headless",
+			expected:    []string{"10.0.0.1:8050", "10.0.0.2:8050"},
+		},
+		{
+			name:        "ordinals starting at 5",
+			topology:    OrdinalPredecessors,
+			ordinal:     6,
+			existing:    []int{6},
+			serviceName: "aeron-headless",
+			start:       5,
+			expected:    []string{"aeron-5.aeron-headless.test-namespace.svc.cluster.local:8050"},
+		},
+		{
+			name:     "no prediction without a governing Service",
+			topology: OrdinalPredecessors,
+			ordinal:  2,
+			existing: []int{2, 1},
+			expected: []string{"10.0.0.2:8050"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statefulSet := createTestStatefulSet(tt.serviceName, tt.start)
+			statefulSet.Spec.Template.Annotations = tt.annotations
+			objects := []runtime.Object{statefulSet}
+			var self corev1.Pod
+			for i, ordinal := range tt.existing {
+				// Later members in existing are older, so creation order disagrees with ordinal order
+				pod := createStatefulSetPod("aeron", ordinal, "", time.Now().Add(time.Duration(-i)*time.Minute))
+				if ordinal == tt.ordinal {
+					self = pod
+				}
+				objects = append(objects, &pod)
+			}
+			// A pod of another StatefulSet is never a neighbor
+			other := createStatefulSetPod("other", 0, "0", time.Now().Add(-time.Hour))
+			objects = append(objects, &other)
+
+			opts := testOptions(fake.NewSimpleClientset(objects...))
+			opts.Ordinal = tt.topology
+			opts.MaxPods = tt.maxPods
+			opts.ResolverNameTemplate = mustParseResolverNameTemplate(t, tt.template)
+			neighbors, _, err := DiscoverOrdinalPeers(context.TODO(), opts, self)
+			if err != nil {
+				t.Fatalf("DiscoverOrdinalPeers() error = %v", err)
+			}
+			var endpoints []string
+			for _, neighbor := range neighbors {
+				endpoints = append(endpoints, neighbor.Endpoint())
+			}
+			if !slices.Equal(endpoints, tt.expected) {
+				t.Errorf("DiscoverOrdinalPeers() = %v, expected %v", endpoints, tt.expected)
+			}
+			if tt.expectedName != "" && (len(neighbors) == 0 || neighbors[0].ResolverName != tt.expectedName ||
+				neighbors[0].IPSource != IPSourceStatefulSetDNS) {
+				t.Errorf("DiscoverOrdinalPeers() = %+v, expected a predicted member named %s", neighbors, tt.expectedName)
+			}
+		})
+	}
+}
+
+func TestDiscoverOrdinalPeersDoesNotPredictSkippedMembers(t *testing.T) {
+	// A member failing Multus validation exists, so its DNS name would reach it on the wrong network
+	invalid := createStatefulSetPod("aeron", 0, "0", time.Now().Add(-10*time.Minute))
+	invalid.Annotations = map[string]string{NetworksAnnotation: "aeron-network"}
+	self := createStatefulSetPod("aeron", 1, "1", time.Now())
+	clientset := fake.NewSimpleClientset(createTestStatefulSet("aeron-headless", 0), &invalid, &self)
+
+	opts := testOptions(clientset)
+	opts.Ordinal = OrdinalFirst
+	neighbors, skipped, err := DiscoverOrdinalPeers(context.TODO(), opts, self)
+	if err != nil {
+		t.Fatalf("DiscoverOrdinalPeers() error = %v", err)
+	}
+	if len(neighbors) != 0 {
+		t.Errorf("DiscoverOrdinalPeers() = %v, expected no neighbors", neighbors)
+	}
+	if len(skipped) != 1 || skipped[0].Name != "aeron-0" {
+		t.Errorf("DiscoverOrdinalPeers() skipped = %v, expected aeron-0", skipped)
+	}
+}
+
+func TestDiscoverOrdinalPeersRequiresStatefulSetMember(t *testing.T) {
+	self := createTestPod("aeron-0", "10.0.0.1", "Running", time.Now())
+	opts := testOptions(fake.NewSimpleClientset(&self))
+	opts.Ordinal = OrdinalPredecessors
+	_, _, err := DiscoverOrdinalPeers(context.TODO(), opts, self)
+	if err == nil || !strings.Contains(err.Error(), "not a StatefulSet member") {
+		t.Errorf("DiscoverOrdinalPeers() error = %v, expected a missing StatefulSet member error", err)
+	}
+}
+
+func TestRenderOrdinalPredecessors(t *testing.T) {
+	// Member 0 was recreated, so is the newest pod, yet member 2 still bootstraps against it first
+	first := createStatefulSetPod("aeron", 0, "0", time.Now())
+	second := createStatefulSetPod("aeron", 1, "1", time.Now().Add(-20*time.Minute))
+	self := createStatefulSetPod("aeron", 2, "2", time.Now().Add(-10*time.Minute))
+	clientset := fake.NewSimpleClientset(createTestStatefulSet("aeron-headless", 0), &first, &second, &self)
+
+	opts := testOptions(clientset)
+	opts.PodName = "aeron-2"
+	opts.Ordinal = OrdinalPredecessors
+	result, err := Render(context.TODO(), opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	expected := RenderProperties([]string{"10.0.0.1:8050", "10.0.0.2:8050"}, 8050, "aeron-2.test-namespace.aeron", "10.0.0.3")
+	if result.Properties != expected {
+		t.Errorf("Render() properties = %q, expected %q", result.Properties, expected)
+	}
+}
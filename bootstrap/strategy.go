@@ -0,0 +1,70 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Strategies for choosing each media driver's neighbors from the whole fabric
+const (
+	// StrategyOldest gives every driver the same oldest pods, as a driver bootstrapping itself would choose
+	StrategyOldest = "oldest"
+	// StrategyRing gives each driver the next pods by age, wrapping around, so no driver is left out of gossip
+	StrategyRing = "ring"
+)
+
+// DefaultRingNeighbors is how many successors each driver is given by StrategyRing when no limit is set
+const DefaultRingNeighbors = 2
+
+// AssignNeighbors chooses the neighbors of every pod with the given strategy, keyed by PodInfo.Key
+// maxNeighbors limits each pod's neighbors, 0 meaning unlimited for StrategyOldest and DefaultRingNeighbors for StrategyRing
+func AssignNeighbors(pods []PodInfo, strategy string, maxNeighbors int) (map[string][]PodInfo, error) {
+	sorted := oldestPods(slices.Clone(pods), 0)
+	assignments := make(map[string][]PodInfo, len(sorted))
+
+	switch strategy {
+	case StrategyOldest, "":
+		oldest := sorted
+		if maxNeighbors > 0 && len(oldest) > maxNeighbors {
+			oldest = oldest[:maxNeighbors]
+		}
+		for _, pod := range sorted {
+			assignments[pod.Key()] = oldest
+		}
+	case StrategyRing:
+		if maxNeighbors <= 0 {
+			maxNeighbors = DefaultRingNeighbors
+		}
+		successors := min(maxNeighbors, len(sorted)-1)
+		for i, pod := range sorted {
+			neighbors := make([]PodInfo, 0, successors)
+			for j := 1; j <= successors; j++ {
+				neighbors = append(neighbors, sorted[(i+j)%len(sorted)])
+			}
+			// A lone driver still bootstraps against itself, as it would on its own
+			if len(neighbors) == 0 {
+				neighbors = append(neighbors, pod)
+			}
+			assignments[pod.Key()] = neighbors
+		}
+	default:
+		return nil, fmt.Errorf("unknown neighbor strategy %q, expected %s or %s", strategy, StrategyOldest, StrategyRing)
+	}
+	return assignments, nil
+}
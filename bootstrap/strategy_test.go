@@ -0,0 +1,101 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAssignNeighbors(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, age time.Duration) PodInfo {
+		return PodInfo{Name: name, IP: "10.0.0.1", Port: 8050, CreationTime: now.Add(-age)}
+	}
+	// Deliberately out of age order
+	pods := []PodInfo{pod("c", time.Minute), pod("a", 3*time.Minute), pod("d", 0), pod("b", 2*time.Minute)}
+
+	names := func(assigned []PodInfo) []string {
+		var result []string
+		for _, p := range assigned {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name         string
+		pods         []PodInfo
+		strategy     string
+		maxNeighbors int
+		expected     map[string][]string
+	}{
+		{
+			name:         "oldest limited",
+			pods:         pods,
+			strategy:     StrategyOldest,
+			maxNeighbors: 2,
+			expected:     map[string][]string{"a": {"a", "b"}, "b": {"a", "b"}, "c": {"a", "b"}, "d": {"a", "b"}},
+		},
+		{
+			name:     "oldest unlimited by default",
+			pods:     pods[:2],
+			strategy: "",
+			expected: map[string][]string{"a": {"a", "c"}, "c": {"a", "c"}},
+		},
+		{
+			name:     "ring with default successors",
+			pods:     pods,
+			strategy: StrategyRing,
+			expected: map[string][]string{"a": {"b", "c"}, "b": {"c", "d"}, "c": {"d", "a"}, "d": {"a", "b"}},
+		},
+		{
+			name:         "ring limited to the other pods",
+			pods:         pods[:2],
+			strategy:     StrategyRing,
+			maxNeighbors: 5,
+			expected:     map[string][]string{"a": {"c"}, "c": {"a"}},
+		},
+		{
+			name:     "ring with a lone pod",
+			pods:     pods[:1],
+			strategy: StrategyRing,
+			expected: map[string][]string{"c": {"c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignments, err := AssignNeighbors(tt.pods, tt.strategy, tt.maxNeighbors)
+			if err != nil {
+				t.Fatalf("AssignNeighbors() error = %v", err)
+			}
+			got := make(map[string][]string, len(assignments))
+			for name, assigned := range assignments {
+				got[name] = names(assigned)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("AssignNeighbors() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := AssignNeighbors(pods, "random", 0); err == nil {
+		t.Errorf("Expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,203 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// NetworkAttachmentDefinitionGVR is the Multus resource defining each network a pod can request
+var NetworkAttachmentDefinitionGVR = schema.GroupVersionResource{
+	Group:    "k8s.cni.cncf.io",
+	Version:  "v1",
+	Resource: "network-attachment-definitions",
+}
+
+// NetworkValidation configures the validating webhook checking media driver pods request the Aeron network
+type NetworkValidation struct {
+	// Dynamic looks up NetworkAttachmentDefinitions, nil to skip that check
+	Dynamic dynamic.Interface
+	// LabelSelector selects the media driver pods, as in Options
+	LabelSelector string
+	// SecondaryInterface is the Aeron network, as in Options
+	SecondaryInterface SecondaryInterface
+	// Enforce rejects inconsistent pods, rather than admitting them with warnings
+	Enforce bool
+	// APITimeout bounds each NetworkAttachmentDefinition lookup
+	APITimeout time.Duration
+}
+
+// requestsNetwork returns whether the element at index in the networks annotation requests the Aeron network, by network
+// name if one is configured, otherwise by interface name, otherwise the default secondary interface
+func requestsNetwork(element NetworkSelectionElement, index int, podNamespace string, secondary SecondaryInterface) bool {
+	switch {
+	case secondary.NetworkName != "":
+		return element.MatchesName(secondary.NetworkName, podNamespace)
+	case secondary.InterfaceName != "":
+		return element.InterfaceName(index) == secondary.InterfaceName
+	default:
+		return element.InterfaceName(index) == DefaultSecondaryInterfaceName
+	}
+}
+
+// CheckNetworkConsistency returns the problems that would stop a pod using the Aeron network once running:
+// a media driver not requesting it, on another interface, or from a NetworkAttachmentDefinition that does not
+// exist, or a pod requesting it without the labels that make it a media driver
+func CheckNetworkConsistency(ctx context.Context, pod v1.Pod, validation NetworkValidation) ([]string, error) {
+	selector := labels.Everything()
+	if validation.LabelSelector != "" {
+		var err error
+		if selector, err = labels.Parse(validation.LabelSelector); err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", validation.LabelSelector, err)
+		}
+	}
+	secondary := PodSecondaryInterface(pod, validation.SecondaryInterface)
+	annotation := pod.Annotations[NetworksAnnotation]
+	if annotation == "" {
+		// Without a network to require, a media driver without Multus uses its pod IP as intended
+		if selector.Matches(labels.Set(pod.Labels)) && secondary.NetworkName != "" {
+			return []string{fmt.Sprintf("media driver pod does not request the Aeron network %s in %s", secondary.NetworkName, NetworksAnnotation)}, nil
+		}
+		return nil, nil
+	}
+
+	elements, err := ParseNetworkSelectionElements(annotation)
+	if err != nil {
+		return []string{fmt.Sprintf("invalid %s annotation: %v", NetworksAnnotation, err)}, nil
+	}
+	aeronIndex := -1
+	for i, element := range elements {
+		if requestsNetwork(element, i, pod.Namespace, secondary) {
+			aeronIndex = i
+			break
+		}
+	}
+
+	var problems []string
+	if !selector.Matches(labels.Set(pod.Labels)) {
+		if aeronIndex >= 0 && secondary.NetworkName != "" {
+			problems = append(problems, fmt.Sprintf("pod requests the Aeron network %s but has no labels matching %q, so it will not be discovered",
+				secondary.NetworkName, validation.LabelSelector))
+		}
+		return problems, nil
+	}
+
+	if aeronIndex < 0 {
+		if secondary.NetworkName != "" {
+			problems = append(problems, fmt.Sprintf("media driver pod requests %s but not the Aeron network %s", annotation, secondary.NetworkName))
+		} else {
+			expected := secondary.InterfaceName
+			if expected == "" {
+				expected = DefaultSecondaryInterfaceName
+			}
+			problems = append(problems, fmt.Sprintf("media driver pod requests no network on the Aeron interface %s", expected))
+		}
+		return problems, nil
+	}
+
+	aeron := elements[aeronIndex]
+	if iface := aeron.InterfaceName(aeronIndex); secondary.InterfaceName != "" && iface != secondary.InterfaceName {
+		problems = append(problems, fmt.Sprintf("Aeron network %s is requested on interface %s, but the bootstrap binds %s",
+			aeron.Name, iface, secondary.InterfaceName))
+	}
+
+	if validation.Dynamic != nil {
+		namespace := aeron.Namespace
+		if namespace == "" {
+			namespace = pod.Namespace
+		}
+		lookupCtx := ctx
+		if validation.APITimeout > 0 {
+			var cancel context.CancelFunc
+			lookupCtx, cancel = context.WithTimeout(ctx, validation.APITimeout)
+			defer cancel()
+		}
+		_, err := validation.Dynamic.Resource(NetworkAttachmentDefinitionGVR).Namespace(namespace).Get(lookupCtx, aeron.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			problems = append(problems, fmt.Sprintf("NetworkAttachmentDefinition %s/%s does not exist", namespace, aeron.Name))
+		} else if err != nil {
+			// Not being able to look, e.g. without Multus installed, is no reason to reject the pod
+			return problems, fmt.Errorf("failed to get NetworkAttachmentDefinition %s/%s: %w", namespace, aeron.Name, err)
+		}
+	}
+	return problems, nil
+}
+
+// ValidateNetworks answers an AdmissionRequest for a pod, rejecting it or warning if it is inconsistent with the Aeron network
+func ValidateNetworks(ctx context.Context, request *admissionv1.AdmissionRequest, validation NetworkValidation) *admissionv1.AdmissionResponse {
+	response := &admissionv1.AdmissionResponse{UID: request.UID, Allowed: true}
+
+	var pod v1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		response.Warnings = []string{fmt.Sprintf("aeron-k8s-bootstrap: failed to decode pod: %v", err)}
+		return response
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = request.Namespace
+	}
+	name := pod.Name
+	if name == "" {
+		name = pod.GenerateName
+	}
+
+	problems, err := CheckNetworkConsistency(ctx, pod, validation)
+	if err != nil {
+		slog.Warn("Failed to validate pod networks", LogKeyPod, name, LogKeyNamespace, pod.Namespace, "error", err)
+		response.Warnings = append(response.Warnings, fmt.Sprintf("aeron-k8s-bootstrap: %v", err))
+	}
+	if len(problems) == 0 {
+		return response
+	}
+
+	slog.Info("Pod networks are inconsistent with the Aeron network", LogKeyPod, name, LogKeyNamespace, pod.Namespace,
+		"problems", problems, "enforce", validation.Enforce)
+	if validation.Enforce {
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusForbidden,
+			Reason:  metav1.StatusReasonForbidden,
+			Message: "aeron-k8s-bootstrap: " + strings.Join(problems, "; "),
+		}
+		return response
+	}
+	for _, problem := range problems {
+		response.Warnings = append(response.Warnings, "aeron-k8s-bootstrap: "+problem)
+	}
+	return response
+}
+
+// ValidationHandler serves the validating admission webhook checking pods against the Aeron network
+func ValidationHandler(validation NetworkValidation) http.Handler {
+	return admissionHandler(func(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		return ValidateNetworks(ctx, request, validation)
+	})
+}
@@ -0,0 +1,234 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// newNetworkDefinitions returns a dynamic client holding the named NetworkAttachmentDefinitions
+func newNetworkDefinitions(t *testing.T, names ...string) *dynamicfake.FakeDynamicClient {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{NetworkAttachmentDefinitionGVR: "NetworkAttachmentDefinitionList"})
+	for _, name := range names {
+		namespace, name, _ := strings.Cut(name, "/")
+		// The tracker can't guess the hyphenated resource name from the kind
+		obj := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "k8s.cni.cncf.io/v1",
+			"kind":       "NetworkAttachmentDefinition",
+			"metadata":   map[string]any{"name": name, "namespace": namespace},
+		}}
+		if err := dynamicClient.Tracker().Create(NetworkAttachmentDefinitionGVR, obj, namespace); err != nil {
+			t.Fatalf("Failed to create NetworkAttachmentDefinition: %v", err)
+		}
+	}
+	return dynamicClient
+}
+
+func TestCheckNetworkConsistency(t *testing.T) {
+	driverLabels := map[string]string{"aeron.io/media-driver": "true"}
+	tests := []struct {
+		name      string
+		labels    map[string]string
+		networks  string
+		declared  string
+		secondary SecondaryInterface
+		problems  []string
+	}{
+		{
+			name:   "driver without multus or a configured network",
+			labels: driverLabels,
+		},
+		{
+			name:      "driver without the configured network",
+			labels:    driverLabels,
+			secondary: SecondaryInterface{NetworkName: "aeron-network"},
+			problems:  []string{"does not request the Aeron network aeron-network"},
+		},
+		{
+			name:      "driver requesting the configured network",
+			labels:    driverLabels,
+			networks:  "storage-network,aeron-network",
+			secondary: SecondaryInterface{NetworkName: "aeron-network"},
+		},
+		{
+			name:      "driver requesting the configured network from its namespace",
+			labels:    driverLabels,
+			networks:  "aeron/aeron-network",
+			secondary: SecondaryInterface{NetworkName: "aeron/aeron-network"},
+		},
+		{
+			name:      "driver requesting another network",
+			labels:    driverLabels,
+			networks:  "storage-network",
+			secondary: SecondaryInterface{NetworkName: "aeron-network"},
+			problems:  []string{"not the Aeron network aeron-network"},
+		},
+		{
+			name:      "driver requesting the network on another interface",
+			labels:    driverLabels,
+			networks:  `[{"name": "aeron-network", "interface": "aeron0"}]`,
+			secondary: SecondaryInterface{NetworkName: "aeron-network", InterfaceName: "net1"},
+			problems:  []string{"requested on interface aeron0, but the bootstrap binds net1"},
+		},
+		{
+			name:      "second network gets net2",
+			labels:    driverLabels,
+			networks:  "storage-network,aeron-network",
+			secondary: SecondaryInterface{NetworkName: "aeron-network", InterfaceName: "net1"},
+			problems:  []string{"requested on interface net2, but the bootstrap binds net1"},
+		},
+		{
+			name:      "interface matched without a network name",
+			labels:    driverLabels,
+			networks:  `[{"name": "storage-network"}, {"name": "aeron-network", "interface": "aeron0"}]`,
+			secondary: SecondaryInterface{InterfaceName: "aeron0"},
+		},
+		{
+			name:      "short form on the configured interface",
+			labels:    driverLabels,
+			networks:  "storage-network,aeron/aeron-network@aeron0",
+			secondary: SecondaryInterface{NetworkName: "aeron-network", InterfaceName: "aeron0"},
+		},
+		{
+			name:      "short form on another interface",
+			labels:    driverLabels,
+			networks:  "aeron-network@eth1",
+			secondary: SecondaryInterface{NetworkName: "aeron-network", InterfaceName: "aeron0"},
+			problems:  []string{"requested on interface eth1, but the bootstrap binds aeron0"},
+		},
+		{
+			name:      "network from another namespace",
+			labels:    driverLabels,
+			networks:  "shared/aeron-network",
+			secondary: SecondaryInterface{NetworkName: "shared/aeron-network"},
+			problems:  []string{"NetworkAttachmentDefinition shared/aeron-network does not exist"},
+		},
+		{
+			name:      "no network on the configured interface",
+			labels:    driverLabels,
+			networks:  "aeron-network",
+			secondary: SecondaryInterface{InterfaceName: "aeron0"},
+			problems:  []string{"no network on the Aeron interface aeron0"},
+		},
+		{
+			name:     "missing network attachment definition",
+			labels:   driverLabels,
+			networks: "other-network",
+			problems: []string{"NetworkAttachmentDefinition aeron/other-network does not exist"},
+		},
+		{
+			name:     "invalid annotation",
+			labels:   driverLabels,
+			networks: `[{"name": 1}]`,
+			problems: []string{"invalid k8s.v1.cni.cncf.io/networks annotation"},
+		},
+		{
+			name:      "driver declaring the network it migrated to",
+			labels:    driverLabels,
+			networks:  "storage-network",
+			declared:  "storage-network",
+			secondary: SecondaryInterface{NetworkName: "aeron-network"},
+		},
+		{
+			name:      "aeron network without media driver labels",
+			labels:    map[string]string{"app": "aeron"},
+			networks:  "aeron-network",
+			secondary: SecondaryInterface{NetworkName: "aeron-network"},
+			problems:  []string{"has no labels matching \"aeron.io/media-driver=true\""},
+		},
+		{
+			name:      "unrelated pod",
+			labels:    map[string]string{"app": "web"},
+			networks:  "storage-network",
+			secondary: SecondaryInterface{NetworkName: "aeron-network"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "aeron-0", Namespace: "aeron", Labels: tt.labels}}
+			pod.Annotations = map[string]string{}
+			if tt.networks != "" {
+				pod.Annotations[NetworksAnnotation] = tt.networks
+			}
+			if tt.declared != "" {
+				pod.Annotations[NetworkAnnotation] = tt.declared
+			}
+			validation := NetworkValidation{
+				Dynamic:            newNetworkDefinitions(t, "aeron/aeron-network", "aeron/storage-network"),
+				LabelSelector:      DefaultLabelSelector,
+				SecondaryInterface: tt.secondary,
+			}
+
+			problems, err := CheckNetworkConsistency(context.TODO(), pod, validation)
+			if err != nil {
+				t.Fatalf("CheckNetworkConsistency() error = %v", err)
+			}
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("CheckNetworkConsistency() = %q, expected %d problems", problems, len(tt.problems))
+			}
+			for i, expected := range tt.problems {
+				if !strings.Contains(problems[i], expected) {
+					t.Errorf("Problem %q does not contain %q", problems[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationWebhook(t *testing.T) {
+	validation := NetworkValidation{
+		Dynamic:            newNetworkDefinitions(t, "aeron/aeron-network"),
+		LabelSelector:      DefaultLabelSelector,
+		SecondaryInterface: SecondaryInterface{NetworkName: "aeron-network", InterfaceName: "aeron0"},
+	}
+	tests := []struct {
+		fixture  string
+		enforce  bool
+		allowed  bool
+		warnings int
+	}{
+		{"aeron-network.json", true, true, 0},
+		{"wrong-network.json", false, true, 1},
+		{"wrong-network.json", true, false, 0},
+		{"unlabeled.json", true, true, 0},
+	}
+	for _, tt := range tests {
+		validation.Enforce = tt.enforce
+		response := reviewFixtureWith(t, ValidationHandler(validation), tt.fixture)
+		if response.Allowed != tt.allowed || len(response.Warnings) != tt.warnings {
+			t.Errorf("%s (enforce %v) = allowed %v with warnings %q, expected allowed %v with %d warnings",
+				tt.fixture, tt.enforce, response.Allowed, response.Warnings, tt.allowed, tt.warnings)
+		}
+		if !response.Allowed && (response.Result == nil || !strings.Contains(response.Result.Message, "not the Aeron network")) {
+			t.Errorf("%s rejected with %+v, expected the problem as its message", tt.fixture, response.Result)
+		}
+		if response.Patch != nil {
+			t.Errorf("%s was patched by the validating webhook", tt.fixture)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// Defaults of the injection webhook
+const (
+	defaultInjectImage = "ghcr.io/james-masson/aeron-k8s-bootstrap/aeron-k8s-bootstrap:latest"
+	injectEnvPrefix    = "AERON_MD_INJECT_ENV_"
+)
+
+// optionsFromEnv builds the bootstrap options from environment variables, falling back to the package defaults
+func optionsFromEnv(clientset kubernetes.Interface, namespace string, metrics *bootstrap.Metrics) bootstrap.Options {
+	return bootstrap.Options{
+		Clientset:            clientset,
+		Namespace:            namespace,
+		PodName:              getCurrentPodName(),
+		LabelSelector:        getLabelSelector(),
+		MaxPods:              getMaxPods(),
+		Ordinal:              getOrdinal(),
+		ClusterDomain:        getClusterDomain(),
+		BootstrapPath:        getBootstrapPath(),
+		Output:               getOutput(),
+		OutputName:           os.Getenv("AERON_MD_OUTPUT_NAME"),
+		Lookup:               getLookup(),
+		LookupPath:           os.Getenv("AERON_MD_LOOKUP_PATH"),
+		ForceConflicts:       getForceConflicts(),
+		Assignment:           getAssignment(),
+		DiscoveryPort:        getDiscoveryPort(),
+		HostnameSuffix:       getHostnameSuffix(),
+		ResolverNameTemplate: getResolverNameTemplate(),
+		ClusterName:          os.Getenv("AERON_MD_CLUSTER_NAME"),
+		SecondaryInterface: bootstrap.SecondaryInterface{
+			NetworkName:   os.Getenv("AERON_MD_SECONDARY_INTERFACE_NETWORK_NAME"),
+			InterfaceName: os.Getenv("AERON_MD_SECONDARY_INTERFACE_NAME"),
+			IPSelection:   getIPSelection(),
+		},
+		HostNetwork:          getHostNetwork(),
+		EmitEvents:           getEmitEvents(),
+		AnnotatePod:          getAnnotatePod(),
+		PublishIdentity:      getPublishIdentity(),
+		APITimeout:           getAPITimeout(),
+		NetworkStatusTimeout: getNetworkStatusTimeout(),
+		Metrics:              metrics,
+	}
+}
+
+// getCurrentNamespace reads the current namespace from the service account token
+func getCurrentNamespace() (string, error) {
+	namespaceFile := "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+	data, err := os.ReadFile(namespaceFile)
+	if err != nil {
+		slog.Warn("Could not read namespace file, using 'default'", "error", err)
+		return "default", nil
+	}
+
+	return string(data), nil
+}
+
+// getLabelSelector returns the label selector from environment variable or default
+func getLabelSelector() string {
+	if selector := os.Getenv("AERON_MD_LABEL_SELECTOR"); selector != "" {
+		return selector
+	}
+	return bootstrap.DefaultLabelSelector
+}
+
+// getBootstrapPath returns the bootstrap file path from environment variable or default
+func getBootstrapPath() string {
+	if path := os.Getenv("AERON_MD_BOOTSTRAP_PATH"); path != "" {
+		return path
+	}
+	return bootstrap.DefaultBootstrapPath
+}
+
+// getMaxPods returns the maximum number of pods to include from environment variable or default
+func getMaxPods() int {
+	if maxStr := os.Getenv("AERON_MD_MAX_BOOTSTRAP_PODS"); maxStr != "" {
+		if max, err := strconv.Atoi(maxStr); err == nil && max >= 0 {
+			return max
+		}
+		slog.Warn("Invalid AERON_MD_MAX_BOOTSTRAP_PODS value, using default 0 (unlimited)", "value", maxStr)
+	}
+	return 0
+}
+
+// getOrdinal returns the ordinal topology StatefulSet members bootstrap in from environment variable or default (unset, by age)
+func getOrdinal() string {
+	topology := os.Getenv("AERON_MD_ORDINAL")
+	if err := bootstrap.ValidateOrdinal(topology); err != nil {
+		slog.Warn("Invalid AERON_MD_ORDINAL value, choosing neighbors by age", "value", topology)
+		return ""
+	}
+	return topology
+}
+
+// getClusterDomain returns the cluster DNS domain from environment variable or default
+func getClusterDomain() string {
+	if domain := os.Getenv("AERON_MD_CLUSTER_DOMAIN"); domain != "" {
+		return domain
+	}
+	return bootstrap.DefaultClusterDomain
+}
+
+// getNamespace returns the namespace from environment variable or discovers it
+func getNamespace() (string, error) {
+	if namespace := os.Getenv("AERON_MD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	return getCurrentNamespace()
+}
+
+// getHostnameSuffix returns the hostname suffix from environment variable or default
+func getHostnameSuffix() string {
+	if suffix := os.Getenv("AERON_MD_HOSTNAME_SUFFIX"); suffix != "" {
+		return suffix
+	}
+	return bootstrap.DefaultHostnameSuffix
+}
+
+// getLookup returns the format of the resolver name lookup written beside the bootstrap properties from environment variable
+// or default (unset, no lookup)
+func getLookup() string {
+	format := os.Getenv("AERON_MD_LOOKUP")
+	if err := bootstrap.ValidateLookup(format); err != nil {
+		slog.Warn("Invalid AERON_MD_LOOKUP value, not writing a lookup", "value", format)
+		return ""
+	}
+	return format
+}
+
+// getResolverNameTemplate returns the parsed resolver name template from environment variable or default (nil, <pod>.<namespace><suffix>)
+func getResolverNameTemplate() *template.Template {
+	if text := os.Getenv("AERON_MD_RESOLVER_NAME_TEMPLATE"); text != "" {
+		tmpl, err := bootstrap.ParseResolverNameTemplate(text)
+		if err == nil {
+			return tmpl
+		}
+		slog.Warn("Invalid AERON_MD_RESOLVER_NAME_TEMPLATE value, using <pod>.<namespace><suffix>", "value", text, "error", err)
+	}
+	return nil
+}
+
+// getDiscoveryPort returns the discovery port from environment variable or default
+func getDiscoveryPort() int {
+	if portStr := os.Getenv("AERON_MD_DISCOVERY_PORT"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil && port > 0 && port <= 65535 {
+			return port
+		}
+		slog.Warn("Invalid AERON_MD_DISCOVERY_PORT value, using default", "value", portStr, "default", bootstrap.DefaultDiscoveryPort)
+	}
+	return bootstrap.DefaultDiscoveryPort
+}
+
+// getRefreshInterval returns how often to refresh the bootstrap file from environment variable or default (0, run once)
+func getRefreshInterval() time.Duration {
+	if intervalStr := os.Getenv("AERON_MD_REFRESH_INTERVAL"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil && interval >= 0 {
+			return interval
+		}
+		slog.Warn("Invalid AERON_MD_REFRESH_INTERVAL value, using default 0 (run once)", "value", intervalStr)
+	}
+	return 0
+}
+
+// getCurrentHostname returns the current pod's hostname
+func getCurrentHostname() string {
+	if hostname := os.Getenv("HOSTNAME"); hostname != "" {
+		return hostname
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	slog.Warn("Could not determine hostname, using 'localhost'")
+	return "localhost"
+}
+
+// getCurrentPodName returns the current pod's name from POD_NAME, set with the downward API,
+// or its hostname, which is the node's under host networking
+func getCurrentPodName() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	return getCurrentHostname()
+}
+
+// getEmitEvents returns whether to record Kubernetes Events, from environment variable or default (true)
+func getEmitEvents() bool {
+	return getBoolEnv("AERON_MD_EMIT_EVENTS", true)
+}
+
+// getAnnotatePod returns whether to annotate our own pod with the bootstrap result, from environment variable or default (false)
+func getAnnotatePod() bool {
+	return getBoolEnv("AERON_MD_ANNOTATE_POD", false)
+}
+
+// getPublishIdentity returns whether to publish our resolver identity as pod annotations, from environment variable or default (true)
+func getPublishIdentity() bool {
+	return getBoolEnv("AERON_MD_PUBLISH_IDENTITY", true)
+}
+
+// getAPITimeout returns the timeout for each individual Kubernetes API call from environment variable or default (10s)
+func getAPITimeout() time.Duration {
+	return getDurationEnv("AERON_MD_API_TIMEOUT", bootstrap.DefaultAPITimeout)
+}
+
+// getDeadline returns the overall deadline for a bootstrap, including retries, from environment variable or default (2m)
+func getDeadline() time.Duration {
+	return getDurationEnv("AERON_MD_DEADLINE", 2*time.Minute)
+}
+
+// getIPSelection returns which address to use of a network carrying several from environment variable or default (first)
+func getIPSelection() string {
+	if rule := os.Getenv("AERON_MD_IP_SELECTION"); rule != "" {
+		if _, err := bootstrap.ParseIPSelection(rule); err == nil {
+			return rule
+		}
+		slog.Warn("Invalid AERON_MD_IP_SELECTION value, using default", "value", rule, "default", bootstrap.IPSelectionFirst)
+	}
+	return bootstrap.IPSelectionFirst
+}
+
+// getNetworkStatusTimeout returns how long to wait for our own pod's Multus network-status from environment variable or default (1m)
+func getNetworkStatusTimeout() time.Duration {
+	return getDurationEnv("AERON_MD_NETWORK_STATUS_TIMEOUT", bootstrap.DefaultNetworkStatusTimeout)
+}
+
+// getOutput returns where the bootstrap properties are written, file, configmap or secret, from environment variable or default (file)
+func getOutput() string {
+	if output := strings.ToLower(os.Getenv("AERON_MD_OUTPUT")); output != "" {
+		switch output {
+		case bootstrap.OutputFile, bootstrap.OutputConfigMap, bootstrap.OutputSecret:
+			return output
+		}
+		slog.Warn("Invalid AERON_MD_OUTPUT value, using default", "value", output, "default", bootstrap.OutputFile)
+	}
+	return bootstrap.OutputFile
+}
+
+// getAssignment returns where to wait for the controller's neighbor assignment, configmap or annotation,
+// from environment variable or default ("" = discover neighbors ourselves)
+func getAssignment() string {
+	if assignment := strings.ToLower(os.Getenv("AERON_MD_ASSIGNMENT")); assignment != "" {
+		switch assignment {
+		case bootstrap.AssignmentConfigMap, bootstrap.AssignmentAnnotation:
+			return assignment
+		}
+		slog.Warn("Invalid AERON_MD_ASSIGNMENT value, discovering neighbors instead", "value", assignment)
+	}
+	return ""
+}
+
+// getForceConflicts returns whether to take over output fields owned by another field manager, from environment variable or default (false)
+func getForceConflicts() bool {
+	return getBoolEnv("AERON_MD_OUTPUT_FORCE_CONFLICTS", false)
+}
+
+// getInjectImage returns the bootstrap image the webhook injects from environment variable or default
+func getInjectImage() string {
+	if image := os.Getenv("AERON_MD_INJECT_IMAGE"); image != "" {
+		return image
+	}
+	return defaultInjectImage
+}
+
+// getInjectPullPolicy returns the pull policy of the injected init container from environment variable or default ("" = cluster default)
+func getInjectPullPolicy() corev1.PullPolicy {
+	if policy := os.Getenv("AERON_MD_INJECT_PULL_POLICY"); policy != "" {
+		switch corev1.PullPolicy(policy) {
+		case corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+			return corev1.PullPolicy(policy)
+		}
+		slog.Warn("Invalid AERON_MD_INJECT_PULL_POLICY value, using the cluster default", "value", policy)
+	}
+	return ""
+}
+
+// getInjectEnv returns the environment passed to injected init containers, AERON_MD_INJECT_ENV_<NAME>=value becoming <NAME>=value
+func getInjectEnv() []corev1.EnvVar {
+	var env []corev1.EnvVar
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if name, ok := strings.CutPrefix(name, injectEnvPrefix); ok && name != "" {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+	slices.SortFunc(env, func(a, b corev1.EnvVar) int { return strings.Compare(a.Name, b.Name) })
+	return env
+}
+
+// getMetricsAddr returns the listen address for the metrics and health endpoints from environment variable or default (disabled)
+func getMetricsAddr() string {
+	return os.Getenv("AERON_MD_METRICS_ADDR")
+}
+
+// getAeronDir returns the media driver's aeron dir from environment variable or the driver's default (/dev/shm/aeron-<user>)
+func getAeronDir() string {
+	if dir := os.Getenv("AERON_DIR"); dir != "" {
+		return dir
+	}
+	username := "default"
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+	return "/dev/shm/aeron-" + username
+}
+
+// getProbeStateFile returns where probe remembers when the driver was first seen isolated, from environment variable or default
+func getProbeStateFile() string {
+	if path := os.Getenv("AERON_MD_PROBE_STATE_FILE"); path != "" {
+		return path
+	}
+	return filepath.Join(os.TempDir(), "aeron-k8s-bootstrap-probe")
+}
+
+// getProbeMinNeighbors returns the fewest resolver neighbors probe requires from environment variable or default (1)
+func getProbeMinNeighbors() int {
+	if minStr := os.Getenv("AERON_MD_PROBE_MIN_NEIGHBORS"); minStr != "" {
+		if min, err := strconv.Atoi(minStr); err == nil && min >= 0 {
+			return min
+		}
+		slog.Warn("Invalid AERON_MD_PROBE_MIN_NEIGHBORS value, using default 1", "value", minStr)
+	}
+	return 1
+}
+
+// getProbeMinPeerFraction returns the fraction of API peers probe requires as neighbors from environment variable or default (0, disabled)
+func getProbeMinPeerFraction() float64 {
+	if fractionStr := os.Getenv("AERON_MD_PROBE_MIN_PEER_FRACTION"); fractionStr != "" {
+		if fraction, err := strconv.ParseFloat(fractionStr, 64); err == nil && fraction >= 0 && fraction <= 1 {
+			return fraction
+		}
+		slog.Warn("Invalid AERON_MD_PROBE_MIN_PEER_FRACTION value, using default 0", "value", fractionStr)
+	}
+	return 0
+}
+
+// getProbeGracePeriod returns how long probe tolerates an isolated driver from environment variable or default (1m)
+func getProbeGracePeriod() time.Duration {
+	return getDurationEnv("AERON_MD_PROBE_GRACE_PERIOD", time.Minute)
+}
+
+// getProbeAPITimeout returns how long probe asks the API for peers from environment variable or default (500ms),
+// kept below the kubelet's default probe timeoutSeconds of 1
+func getProbeAPITimeout() time.Duration {
+	return getDurationEnv("AERON_MD_PROBE_API_TIMEOUT", 500*time.Millisecond)
+}
+
+// getBoolEnv parses a boolean environment variable, falling back to the default if unset or invalid
+func getBoolEnv(name string, defaultValue bool) bool {
+	if valueStr := os.Getenv(name); valueStr != "" {
+		if value, err := strconv.ParseBool(valueStr); err == nil {
+			return value
+		}
+		slog.Warn("Invalid boolean environment variable, using default", "name", name, "value", valueStr, "default", defaultValue)
+	}
+	return defaultValue
+}
+
+// getDurationEnv parses a positive duration environment variable, falling back to the default if unset or invalid
+func getDurationEnv(name string, defaultValue time.Duration) time.Duration {
+	if valueStr := os.Getenv(name); valueStr != "" {
+		if value, err := time.ParseDuration(valueStr); err == nil && value > 0 {
+			return value
+		}
+		slog.Warn("Invalid duration environment variable, using default", "name", name, "value", valueStr, "default", defaultValue)
+	}
+	return defaultValue
+}
+
+// getHostNetwork returns where host-network pods' addresses are taken from and how they are named,
+// from environment variables or defaults (PodIP, pod)
+func getHostNetwork() bootstrap.HostNetwork {
+	var hostNetwork bootstrap.HostNetwork
+	if address := os.Getenv("AERON_MD_HOST_NETWORK_ADDRESS"); address != "" {
+		if err := (bootstrap.HostNetwork{Address: address}).Validate(); err == nil {
+			hostNetwork.Address = address
+		} else {
+			slog.Warn("Invalid AERON_MD_HOST_NETWORK_ADDRESS value, using default", "value", address, "default", bootstrap.HostNetworkAddressPodIP)
+		}
+	}
+	if naming := os.Getenv("AERON_MD_HOST_NETWORK_NAMING"); naming != "" {
+		if err := (bootstrap.HostNetwork{Naming: naming}).Validate(); err == nil {
+			hostNetwork.Naming = naming
+		} else {
+			slog.Warn("Invalid AERON_MD_HOST_NETWORK_NAMING value, using default", "value", naming, "default", bootstrap.HostNetworkNamingPod)
+		}
+	}
+	return hostNetwork
+}
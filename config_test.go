@@ -0,0 +1,672 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+func TestGetOrdinal(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "by age when env not set", envValue: "", expected: ""},
+		{name: "predecessors", envValue: "predecessors", expected: bootstrap.OrdinalPredecessors},
+		{name: "first", envValue: "first", expected: bootstrap.OrdinalFirst},
+		{name: "invalid topology uses age", envValue: "ring", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_ORDINAL", tt.envValue)
+			if result := getOrdinal(); result != tt.expected {
+				t.Errorf("getOrdinal() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetClusterDomain(t *testing.T) {
+	t.Setenv("AERON_MD_CLUSTER_DOMAIN", "")
+	if result := getClusterDomain(); result != bootstrap.DefaultClusterDomain {
+		t.Errorf("getClusterDomain() = %q, expected %q", result, bootstrap.DefaultClusterDomain)
+	}
+	t.Setenv("AERON_MD_CLUSTER_DOMAIN", "prod.example")
+	if result := getClusterDomain(); result != "prod.example" {
+		t.Errorf("getClusterDomain() = %q, expected prod.example", result)
+	}
+}
+
+func TestGetLookup(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "disabled when env not set", envValue: "", expected: ""},
+		{name: "hosts", envValue: "hosts", expected: bootstrap.LookupHosts},
+		{name: "properties", envValue: "properties", expected: bootstrap.LookupProperties},
+		{name: "invalid format disables", envValue: "json", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_LOOKUP", tt.envValue)
+			if result := getLookup(); result != tt.expected {
+				t.Errorf("getLookup() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetResolverNameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "unset when env not set", envValue: "", expected: ""},
+		{name: "valid template", envValue: "{{.StatefulSet}}-{{.Ordinal}}.{{.Cluster}}{{.Suffix}}", expected: "{{.StatefulSet}}-{{.Ordinal}}.{{.Cluster}}{{.Suffix}}"},
+		{name: "unparseable template uses default", envValue: "{{.Name", expected: ""},
+		{name: "unknown field uses default", envValue: "{{.Hostname}}.aeron", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_RESOLVER_NAME_TEMPLATE", tt.envValue)
+			result := getResolverNameTemplate()
+			text := ""
+			if result != nil {
+				text = result.Root.String()
+			}
+			if text != tt.expected {
+				t.Errorf("getResolverNameTemplate() = %q, expected %q", text, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetDiscoveryPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{
+			name:     "default port when env not set",
+			envValue: "",
+			expected: 8050,
+		},
+		{
+			name:     "valid env port",
+			envValue: "9090",
+			expected: 9090,
+		},
+		{
+			name:     "invalid env port - non-numeric",
+			envValue: "invalid",
+			expected: 8050,
+		},
+		{
+			name:     "invalid env port - out of range",
+			envValue: "99999",
+			expected: 8050,
+		},
+		{
+			name:     "invalid env port - zero",
+			envValue: "0",
+			expected: 8050,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("AERON_MD_DISCOVERY_PORT", tt.envValue)
+			} else {
+				os.Unsetenv("AERON_MD_DISCOVERY_PORT")
+			}
+
+			result := getDiscoveryPort()
+			if result != tt.expected {
+				t.Errorf("getDiscoveryPort() = %d, expected %d", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{
+			name:     "default label when env not set",
+			envValue: "",
+			expected: "aeron.io/media-driver=true",
+		},
+		{
+			name:     "custom label from environment",
+			envValue: "app=aeron,version=1.0",
+			expected: "app=aeron,version=1.0",
+		},
+		{
+			name:     "single custom label",
+			envValue: "service=media-driver",
+			expected: "service=media-driver",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("AERON_MD_LABEL_SELECTOR", tt.envValue)
+			} else {
+				os.Unsetenv("AERON_MD_LABEL_SELECTOR")
+			}
+
+			result := getLabelSelector()
+			if result != tt.expected {
+				t.Errorf("getLabelSelector() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetBootstrapPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{
+			name:     "default path when env not set",
+			envValue: "",
+			expected: "/etc/aeron/bootstrap.properties",
+		},
+		{
+			name:     "custom path from environment",
+			envValue: "/custom/path/bootstrap.properties",
+			expected: "/custom/path/bootstrap.properties",
+		},
+		{
+			name:     "relative path",
+			envValue: "./config/bootstrap.properties",
+			expected: "./config/bootstrap.properties",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("AERON_MD_BOOTSTRAP_PATH", tt.envValue)
+			} else {
+				os.Unsetenv("AERON_MD_BOOTSTRAP_PATH")
+			}
+
+			result := getBootstrapPath()
+			if result != tt.expected {
+				t.Errorf("getBootstrapPath() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetMaxPods(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected int
+	}{
+		{
+			name:     "default max pods when env not set",
+			envValue: "",
+			expected: 0,
+		},
+		{
+			name:     "valid max pods",
+			envValue: "5",
+			expected: 5,
+		},
+		{
+			name:     "zero max pods (unlimited)",
+			envValue: "0",
+			expected: 0,
+		},
+		{
+			name:     "large max pods",
+			envValue: "100",
+			expected: 100,
+		},
+		{
+			name:     "invalid env value - non-numeric",
+			envValue: "invalid",
+			expected: 0,
+		},
+		{
+			name:     "invalid env value - negative",
+			envValue: "-5",
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("AERON_MD_MAX_BOOTSTRAP_PODS", tt.envValue)
+			} else {
+				os.Unsetenv("AERON_MD_MAX_BOOTSTRAP_PODS")
+			}
+
+			result := getMaxPods()
+			if result != tt.expected {
+				t.Errorf("getMaxPods() = %d, expected %d", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetNamespace(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		expectError  bool
+		expectedName string
+	}{
+		{
+			name:         "custom namespace from environment",
+			envValue:     "custom-namespace",
+			expectError:  false,
+			expectedName: "custom-namespace",
+		},
+		{
+			name:         "production namespace",
+			envValue:     "production",
+			expectError:  false,
+			expectedName: "production",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("AERON_MD_NAMESPACE", tt.envValue)
+			} else {
+				os.Unsetenv("AERON_MD_NAMESPACE")
+			}
+
+			result, err := getNamespace()
+
+			if tt.expectError && err == nil {
+				t.Errorf("Expected error but got none")
+				return
+			}
+
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			if !tt.expectError && result != tt.expectedName {
+				t.Errorf("getNamespace() = %s, expected %s", result, tt.expectedName)
+			}
+		})
+	}
+}
+
+func TestGetHostnameSuffix(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{
+			name:     "default suffix when env not set",
+			envValue: "",
+			expected: ".aeron",
+		},
+		{
+			name:     "custom suffix from environment",
+			envValue: ".custom",
+			expected: ".custom",
+		},
+		{
+			name:     "suffix without dot",
+			envValue: "mysuffix",
+			expected: "mysuffix",
+		},
+		{
+			name:     "empty suffix",
+			envValue: "",
+			expected: ".aeron",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("AERON_MD_HOSTNAME_SUFFIX", tt.envValue)
+			} else {
+				os.Unsetenv("AERON_MD_HOSTNAME_SUFFIX")
+			}
+
+			result := getHostnameSuffix()
+			if result != tt.expected {
+				t.Errorf("getHostnameSuffix() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetCurrentHostname(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{
+			name:     "hostname from environment",
+			envValue: "test-pod-123",
+			expected: "test-pod-123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			// Set test env value
+			if tt.envValue != "" {
+				t.Setenv("HOSTNAME", tt.envValue)
+			} else {
+				os.Unsetenv("HOSTNAME")
+			}
+
+			result := getCurrentHostname()
+			if result != tt.expected {
+				t.Errorf("getCurrentHostname() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetCurrentPodName(t *testing.T) {
+	tests := []struct {
+		name     string
+		podName  string
+		hostname string
+		expected string
+	}{
+		{name: "POD_NAME from the downward API", podName: "aeron-0", hostname: "worker-1", expected: "aeron-0"},
+		{name: "hostname when POD_NAME not set", podName: "", hostname: "aeron-1", expected: "aeron-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POD_NAME", tt.podName)
+			t.Setenv("HOSTNAME", tt.hostname)
+			if result := getCurrentPodName(); result != tt.expected {
+				t.Errorf("getCurrentPodName() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetBoolEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		envValue     string
+		defaultValue bool
+		expected     bool
+	}{
+		{name: "unset uses default", envValue: "", defaultValue: true, expected: true},
+		{name: "false", envValue: "false", defaultValue: true, expected: false},
+		{name: "true", envValue: "1", defaultValue: false, expected: true},
+		{name: "invalid uses default", envValue: "maybe", defaultValue: false, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_TEST_BOOL", tt.envValue)
+			if result := getBoolEnv("AERON_MD_TEST_BOOL", tt.defaultValue); result != tt.expected {
+				t.Errorf("getBoolEnv() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetDurationEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected time.Duration
+	}{
+		{name: "default when env not set", envValue: "", expected: time.Minute},
+		{name: "valid duration", envValue: "5s", expected: 5 * time.Second},
+		{name: "invalid duration uses default", envValue: "later", expected: time.Minute},
+		{name: "zero uses default", envValue: "0s", expected: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_TEST_DURATION", tt.envValue)
+			if result := getDurationEnv("AERON_MD_TEST_DURATION", time.Minute); result != tt.expected {
+				t.Errorf("getDurationEnv() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected time.Duration
+	}{
+		{name: "default runs once", envValue: "", expected: 0},
+		{name: "valid interval", envValue: "30s", expected: 30 * time.Second},
+		{name: "invalid interval uses default", envValue: "soon", expected: 0},
+		{name: "negative interval uses default", envValue: "-1m", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_REFRESH_INTERVAL", tt.envValue)
+			if result := getRefreshInterval(); result != tt.expected {
+				t.Errorf("getRefreshInterval() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("HOSTNAME", "aeron-0")
+	t.Setenv("AERON_MD_DISCOVERY_PORT", "9050")
+	t.Setenv("AERON_MD_SECONDARY_INTERFACE_NAME", "net2")
+	t.Setenv("AERON_MD_EMIT_EVENTS", "false")
+
+	opts := optionsFromEnv(nil, "test-namespace", nil)
+
+	if opts.Namespace != "test-namespace" || opts.PodName != "aeron-0" {
+		t.Errorf("optionsFromEnv() pod = %s/%s, expected test-namespace/aeron-0", opts.Namespace, opts.PodName)
+	}
+	if opts.DiscoveryPort != 9050 || opts.SecondaryInterface.InterfaceName != "net2" || opts.EmitEvents {
+		t.Errorf("optionsFromEnv() did not apply environment variables: %+v", opts)
+	}
+	if opts.LabelSelector != bootstrap.DefaultLabelSelector || !opts.PublishIdentity || opts.NetworkStatusTimeout != bootstrap.DefaultNetworkStatusTimeout {
+		t.Errorf("optionsFromEnv() did not apply defaults: %+v", opts)
+	}
+}
+
+func TestGetOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "default output when env not set", envValue: "", expected: bootstrap.OutputFile},
+		{name: "configmap", envValue: "configmap", expected: bootstrap.OutputConfigMap},
+		{name: "upper case Secret", envValue: "Secret", expected: bootstrap.OutputSecret},
+		{name: "invalid output uses default", envValue: "s3", expected: bootstrap.OutputFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_OUTPUT", tt.envValue)
+			if result := getOutput(); result != tt.expected {
+				t.Errorf("getOutput() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetAssignment(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "discover when env not set", envValue: "", expected: ""},
+		{name: "configmap", envValue: "configmap", expected: bootstrap.AssignmentConfigMap},
+		{name: "upper case Annotation", envValue: "Annotation", expected: bootstrap.AssignmentAnnotation},
+		{name: "invalid assignment discovers", envValue: "crd", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_ASSIGNMENT", tt.envValue)
+			if result := getAssignment(); result != tt.expected {
+				t.Errorf("getAssignment() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetInjectPullPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected corev1.PullPolicy
+	}{
+		{name: "cluster default when env not set", envValue: "", expected: ""},
+		{name: "IfNotPresent", envValue: "IfNotPresent", expected: corev1.PullIfNotPresent},
+		{name: "invalid policy uses cluster default", envValue: "Sometimes", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_INJECT_PULL_POLICY", tt.envValue)
+			if result := getInjectPullPolicy(); result != tt.expected {
+				t.Errorf("getInjectPullPolicy() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetInjectEnv(t *testing.T) {
+	t.Setenv("AERON_MD_INJECT_ENV_AERON_MD_DISCOVERY_PORT", "8050")
+	t.Setenv("AERON_MD_INJECT_ENV_AERON_MD_HOSTNAME_SUFFIX", ".aeron.svc")
+	t.Setenv("AERON_MD_INJECT_ENV_", "ignored")
+
+	expected := []corev1.EnvVar{
+		{Name: "AERON_MD_DISCOVERY_PORT", Value: "8050"},
+		{Name: "AERON_MD_HOSTNAME_SUFFIX", Value: ".aeron.svc"},
+	}
+	if result := getInjectEnv(); !slices.Equal(result, expected) {
+		t.Errorf("getInjectEnv() = %v, expected %v", result, expected)
+	}
+}
+
+func TestGetIPSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "first when env not set", envValue: "", expected: bootstrap.IPSelectionFirst},
+		{name: "family", envValue: "ipv6", expected: bootstrap.IPSelectionIPv6},
+		{name: "cidr", envValue: "cidr:192.168.0.0/16", expected: "cidr:192.168.0.0/16"},
+		{name: "index", envValue: "index:1", expected: "index:1"},
+		{name: "invalid cidr uses default", envValue: "cidr:192.168.0.0", expected: bootstrap.IPSelectionFirst},
+		{name: "invalid rule uses default", envValue: "last", expected: bootstrap.IPSelectionFirst},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_IP_SELECTION", tt.envValue)
+			if result := getIPSelection(); result != tt.expected {
+				t.Errorf("getIPSelection() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetHostNetwork(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		naming   string
+		expected bootstrap.HostNetwork
+	}{
+		{name: "pod IP and naming when env not set", expected: bootstrap.HostNetwork{}},
+		{
+			name:     "node address and naming",
+			address:  "ExternalIP",
+			naming:   "node",
+			expected: bootstrap.HostNetwork{Address: bootstrap.HostNetworkAddressExternalIP, Naming: bootstrap.HostNetworkNamingNode},
+		},
+		{
+			name:     "node annotation",
+			address:  "annotation:example.com/fabric-ip",
+			expected: bootstrap.HostNetwork{Address: "annotation:example.com/fabric-ip"},
+		},
+		{
+			name:     "invalid values use defaults independently",
+			address:  "Hostname",
+			naming:   "node",
+			expected: bootstrap.HostNetwork{Naming: bootstrap.HostNetworkNamingNode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_HOST_NETWORK_ADDRESS", tt.address)
+			t.Setenv("AERON_MD_HOST_NETWORK_NAMING", tt.naming)
+			if result := getHostNetwork(); result != tt.expected {
+				t.Errorf("getHostNetwork() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
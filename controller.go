@@ -0,0 +1,191 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// Leader election timings, the client-go defaults used by kube-controller-manager
+var (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// runController assigns neighbors to every media driver pod from a single elected leader,
+// so per-pod init containers only wait for and read their assignment
+func runController(args []string) error {
+	flags := flag.NewFlagSet("controller", flag.ContinueOnError)
+	strategy := flags.String("strategy", bootstrap.StrategyOldest, "neighbor strategy, oldest or ring")
+	publish := flags.String("publish", bootstrap.AssignmentConfigMap, "where assignments are published, configmap or annotation")
+	fabricConfigMap := flags.String("fabric-configmap", "", "shared ConfigMap describing every member of the fabric, empty to disable")
+	leaseName := flags.String("lease-name", "aeron-k8s-bootstrap-controller", "Lease used for leader election")
+	resync := flags.Duration("resync", 5*time.Minute, "re-reconcile this often even without pod changes, 0 to disable")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *strategy != bootstrap.StrategyOldest && *strategy != bootstrap.StrategyRing {
+		return fmt.Errorf("unknown strategy %q, expected %s or %s", *strategy, bootstrap.StrategyOldest, bootstrap.StrategyRing)
+	}
+	if *publish != bootstrap.AssignmentConfigMap && *publish != bootstrap.AssignmentAnnotation {
+		return fmt.Errorf("unknown publish target %q, expected %s or %s", *publish, bootstrap.AssignmentConfigMap, bootstrap.AssignmentAnnotation)
+	}
+
+	opts, err := clusterOptions()
+	if err != nil {
+		return err
+	}
+	copts := bootstrap.ControllerOptions{
+		Options:         opts,
+		Strategy:        *strategy,
+		Publish:         *publish,
+		FabricConfigMap: *fabricConfigMap,
+		ResyncPeriod:    *resync,
+	}
+
+	return runLeaderElected(*leaseName, opts, func(ctx context.Context) error {
+		return bootstrap.RunController(ctx, copts)
+	})
+}
+
+// runFabricController reconciles every AeronMediaDriverFabric from a single elected leader
+func runFabricController(args []string) error {
+	flags := flag.NewFlagSet("fabric-controller", flag.ContinueOnError)
+	watchNamespace := flags.String("watch-namespace", "", "namespace to watch for fabrics, empty for every namespace")
+	leaseName := flags.String("lease-name", "aeron-k8s-bootstrap-fabric-controller", "Lease used for leader election")
+	resync := flags.Duration("resync", 5*time.Minute, "re-reconcile this often even without changes, 0 to disable")
+	podSelector := flags.String("pod-selector", bootstrap.DefaultLabelSelector, "label selector of the pods to cache, which fabric selectors must narrow, empty for every pod")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if _, err := labels.Parse(*podSelector); err != nil {
+		return fmt.Errorf("invalid -pod-selector %q: %w", *podSelector, err)
+	}
+
+	opts, err := clusterOptions()
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := getInClusterDynamicClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	fopts := bootstrap.FabricControllerOptions{
+		Clientset:    opts.Clientset,
+		Dynamic:      dynamicClient,
+		Namespace:    *watchNamespace,
+		PodSelector:  *podSelector,
+		ResyncPeriod: *resync,
+	}
+
+	return runLeaderElected(*leaseName, opts, func(ctx context.Context) error {
+		return bootstrap.RunFabricController(ctx, fopts)
+	})
+}
+
+// runLeaderElected runs fn while this pod holds the named Lease in its namespace, until interrupted
+// or fn returns, stepping down so another replica takes over. Losing the Lease stops fn and is an error,
+// so the pod restarts and stands for election again
+func runLeaderElected(leaseName string, opts bootstrap.Options, fn func(ctx context.Context) error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: leaseName, Namespace: opts.Namespace},
+		Client:     opts.Clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: opts.PodName},
+	}
+
+	// OnStartedLeading runs in its own goroutine, which may not have started fn when RunOrDie returns
+	var (
+		mu       sync.Mutex
+		running  bool
+		finished bool
+		fnDone   = make(chan error, 1)
+	)
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				if finished {
+					mu.Unlock()
+					return
+				}
+				running = true
+				mu.Unlock()
+
+				slog.Info("Elected controller leader", bootstrap.LogKeyPod, opts.PodName, bootstrap.LogKeyNamespace, opts.Namespace, "lease", leaseName)
+				fnDone <- fn(ctx)
+				// Step down, so another replica takes over rather than nobody reconciling
+				stop()
+			},
+			OnStoppedLeading: func() {
+				slog.Info("No longer the controller leader", bootstrap.LogKeyPod, opts.PodName, "lease", leaseName)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != opts.PodName {
+					slog.Info("Following controller leader", "leader", identity, "lease", leaseName)
+				}
+			},
+		},
+	})
+	// RunOrDie only returns before we are interrupted or fn returns when the Lease was lost
+	lost := ctx.Err() == nil
+
+	mu.Lock()
+	finished = true
+	wasRunning := running
+	mu.Unlock()
+
+	// The context passed to fn is cancelled once RunOrDie returns, so wait for it to stop publishing
+	var err error
+	if wasRunning {
+		err = <-fnDone
+	}
+	if lost {
+		return fmt.Errorf("lost the controller lease %s", leaseName)
+	}
+	return err
+}
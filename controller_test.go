@@ -0,0 +1,98 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+func TestRunControllerRejectsInvalidFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "unknown strategy", args: []string{"-strategy", "random"}},
+		{name: "unknown publish target", args: []string{"-publish", "crd"}},
+		{name: "unknown flag", args: []string{"-frobnicate"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runController(tt.args); err == nil {
+				t.Errorf("runController(%v) expected an error", tt.args)
+			}
+		})
+	}
+}
+
+func TestRunFabricControllerRejectsUnknownFlags(t *testing.T) {
+	if err := runFabricController([]string{"-frobnicate"}); err == nil {
+		t.Errorf("Expected an error for an unknown flag")
+	}
+	if err := runFabricController([]string{"-pod-selector", "app in (a"}); err == nil {
+		t.Errorf("Expected an error for an invalid pod selector")
+	}
+}
+
+func TestRunLeaderElectedLosingLease(t *testing.T) {
+	leaseDuration, renewDeadline, retryPeriod = 300*time.Millisecond, 200*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { leaseDuration, renewDeadline, retryPeriod = 15*time.Second, 10*time.Second, 2*time.Second })
+
+	clientset := fake.NewSimpleClientset()
+	opts := bootstrap.Options{Clientset: clientset, Namespace: "test-namespace", PodName: "controller-0"}
+
+	// Once partitioned, e.g. from the API server, the Lease can no longer be renewed
+	var partitioned, stopped atomic.Bool
+	clientset.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if partitioned.Load() {
+			return true, nil, errors.New("API server unreachable")
+		}
+		return false, nil, nil
+	})
+
+	err := runLeaderElected("aeron-controller", opts, func(ctx context.Context) error {
+		partitioned.Store(true)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		stopped.Store(true)
+		return nil
+	})
+	if err == nil {
+		t.Errorf("Expected an error after losing the Lease")
+	}
+	if !stopped.Load() {
+		t.Errorf("Expected runLeaderElected to wait for fn to stop")
+	}
+}
+
+func TestRunLeaderElectedReturnsError(t *testing.T) {
+	opts := bootstrap.Options{Clientset: fake.NewSimpleClientset(), Namespace: "test-namespace", PodName: "controller-0"}
+	want := errors.New("reconcile failed")
+	if err := runLeaderElected("aeron-controller", opts, func(ctx context.Context) error { return want }); !errors.Is(err, want) {
+		t.Errorf("runLeaderElected() error = %v, expected %v", err, want)
+	}
+}
@@ -0,0 +1,136 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// errDiffFound means the bootstrap file would change if the bootstrap ran now
+var errDiffFound = errors.New("bootstrap file differs from the generated configuration")
+
+// clusterOptions connects to the Kubernetes API and reads bootstrap.Options from the environment
+func clusterOptions() (bootstrap.Options, error) {
+	clientset, err := getInClusterConfig()
+	if err != nil {
+		return bootstrap.Options{}, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	namespace, err := getNamespace()
+	if err != nil {
+		return bootstrap.Options{}, fmt.Errorf("failed to determine namespace: %w", err)
+	}
+	return optionsFromEnv(clientset, namespace, nil), nil
+}
+
+// renderFromEnv renders the bootstrap properties as configured by the environment, without writing anything
+func renderFromEnv() (bootstrap.Result, error) {
+	opts, err := clusterOptions()
+	if err != nil {
+		return bootstrap.Result{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getDeadline())
+	defer cancel()
+
+	return bootstrap.Render(ctx, opts)
+}
+
+// runDryRun prints the bootstrap properties that would be written, without touching the file or the pod
+func runDryRun() error {
+	result, err := renderFromEnv()
+	if err != nil {
+		return err
+	}
+	fmt.Print(result.Properties)
+	return nil
+}
+
+// runDiff compares the bootstrap properties that would be written now with those last written to the configured output,
+// the bootstrap file or our pod's ConfigMap or Secret, failing with errDiffFound if they differ
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	path := flags.String("file", getBootstrapPath(), "existing bootstrap properties file to compare against, with AERON_MD_OUTPUT=file")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	fileSet := false
+	flags.Visit(func(f *flag.Flag) { fileSet = fileSet || f.Name == "file" })
+
+	opts, err := clusterOptions()
+	if err != nil {
+		return err
+	}
+	if fileSet && opts.Output != bootstrap.OutputFile {
+		return fmt.Errorf("-file cannot be used with AERON_MD_OUTPUT=%s, which is compared against its %s key instead",
+			opts.Output, bootstrap.PropertiesKey)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getDeadline())
+	defer cancel()
+
+	// Output not written yet is reported as every line being added
+	outputOpts := opts
+	outputOpts.BootstrapPath = *path
+	existing, location, err := bootstrap.ReadOutput(ctx, outputOpts)
+	if err != nil {
+		return err
+	}
+
+	result, err := bootstrap.Render(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	diff := bootstrap.DiffProperties(existing, result.Properties)
+	printDiff(os.Stdout, diff)
+	if !diff.Empty() {
+		return fmt.Errorf("%w: %s", errDiffFound, location)
+	}
+	slog.Info("Bootstrap properties are up to date", "output", location)
+	return nil
+}
+
+// printDiff writes one line per added or removed neighbor and changed key
+func printDiff(w io.Writer, diff bootstrap.PropertiesDiff) {
+	for _, neighbor := range diff.AddedNeighbors {
+		fmt.Fprintf(w, "+ neighbor %s\n", neighbor)
+	}
+	for _, neighbor := range diff.RemovedNeighbors {
+		fmt.Fprintf(w, "- neighbor %s\n", neighbor)
+	}
+	for _, change := range diff.ChangedKeys {
+		switch {
+		case change.Old == "":
+			fmt.Fprintf(w, "+ %s=%s\n", change.Key, change.New)
+		case change.New == "":
+			fmt.Fprintf(w, "- %s=%s\n", change.Key, change.Old)
+		default:
+			fmt.Fprintf(w, "~ %s=%s -> %s\n", change.Key, change.Old, change.New)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+func TestPrintDiff(t *testing.T) {
+	diff := bootstrap.PropertiesDiff{
+		AddedNeighbors:   []string{"10.0.0.3:8050"},
+		RemovedNeighbors: []string{"10.0.0.2:8050"},
+		ChangedKeys: []bootstrap.KeyChange{
+			{Key: "aeron.driver.resolver.interface", Old: "10.0.0.1:8050", New: "10.0.0.9:8050"},
+			{Key: "aeron.driver.resolver.name", New: "aeron-0.aeron"},
+			{Key: "aeron.name.resolver.supplier", Old: "driver"},
+		},
+	}
+	expected := "+ neighbor 10.0.0.3:8050\n" +
+		"- neighbor 10.0.0.2:8050\n" +
+		"~ aeron.driver.resolver.interface=10.0.0.1:8050 -> 10.0.0.9:8050\n" +
+		"+ aeron.driver.resolver.name=aeron-0.aeron\n" +
+		"- aeron.name.resolver.supplier=driver\n"
+
+	var out bytes.Buffer
+	printDiff(&out, diff)
+	if out.String() != expected {
+		t.Errorf("printDiff() = %q, expected %q", out.String(), expected)
+	}
+
+	out.Reset()
+	printDiff(&out, bootstrap.PropertiesDiff{})
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for an empty diff, got %q", out.String())
+	}
+}
+
+func TestRunBootstrapRejectsArguments(t *testing.T) {
+	if err := dispatch([]string{"-dry-run", "extra"}); err == nil {
+		t.Errorf("Expected an error for unexpected arguments")
+	}
+	if err := dispatch([]string{"-frobnicate"}); err == nil {
+		t.Errorf("Expected an error for an unknown flag")
+	}
+}
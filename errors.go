@@ -0,0 +1,66 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// Process exit codes, documented in the README
+const (
+	exitOK             = 0
+	exitFailure        = 1
+	exitNoPeers        = 2
+	exitSelfNotFound   = 3
+	exitMultusNotReady = 4
+	exitAPIUnavailable = 5
+	exitVerifyFailed   = 6
+	exitProbeFailed    = 7
+	exitDiffFound      = 8
+	exitOutputConflict = 9
+	exitNoAssignment   = 10
+)
+
+// exitCode maps an error returned by run to the process exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, bootstrap.ErrNoPeers):
+		return exitNoPeers
+	case errors.Is(err, bootstrap.ErrSelfNotFound):
+		return exitSelfNotFound
+	case errors.Is(err, bootstrap.ErrMultusNotReady):
+		return exitMultusNotReady
+	case errors.Is(err, bootstrap.ErrAPIUnavailable):
+		return exitAPIUnavailable
+	case errors.Is(err, errVerifyFailed):
+		return exitVerifyFailed
+	case errors.Is(err, errProbeFailed):
+		return exitProbeFailed
+	case errors.Is(err, errDiffFound):
+		return exitDiffFound
+	case errors.Is(err, bootstrap.ErrOutputConflict):
+		return exitOutputConflict
+	case errors.Is(err, bootstrap.ErrNoAssignment):
+		return exitNoAssignment
+	default:
+		return exitFailure
+	}
+}
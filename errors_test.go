@@ -0,0 +1,55 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{name: "success", err: nil, expected: exitOK},
+		{name: "no peers", err: bootstrap.ErrNoPeers, expected: exitNoPeers},
+		{name: "wrapped self not found", err: fmt.Errorf("%w: pod aeron-0", bootstrap.ErrSelfNotFound), expected: exitSelfNotFound},
+		{name: "wrapped multus not ready", err: fmt.Errorf("bootstrap: %w", bootstrap.ErrMultusNotReady), expected: exitMultusNotReady},
+		{name: "api unavailable", err: fmt.Errorf("%w: list_pods: %w", bootstrap.ErrAPIUnavailable, apierrors.NewServiceUnavailable("down")), expected: exitAPIUnavailable},
+		{name: "verify failed", err: fmt.Errorf("%w: resolver has 1 neighbors, expected 2", errVerifyFailed), expected: exitVerifyFailed},
+		{name: "probe failed", err: fmt.Errorf("%w: isolated", errProbeFailed), expected: exitProbeFailed},
+		{name: "output conflict", err: fmt.Errorf("%w: ConfigMap aeron-0-aeron-bootstrap", bootstrap.ErrOutputConflict), expected: exitOutputConflict},
+		{name: "no assignment", err: fmt.Errorf("%w: pod aeron-0", bootstrap.ErrNoAssignment), expected: exitNoAssignment},
+		{name: "diff found", err: fmt.Errorf("%w: /etc/aeron/bootstrap.properties", errDiffFound), expected: exitDiffFound},
+		{name: "anything else", err: errors.New("disk full"), expected: exitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := exitCode(tt.err); result != tt.expected {
+				t.Errorf("exitCode(%v) = %d, expected %d", tt.err, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// runExplain prints why each pod matching the label selector was included as a bootstrap neighbor or excluded
+func runExplain(args []string) error {
+	flags := flag.NewFlagSet("explain", flag.ContinueOnError)
+	output := flags.String("output", "table", "output format, table or json")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("unknown output format %q, expected table or json", *output)
+	}
+
+	opts, err := clusterOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getDeadline())
+	defer cancel()
+
+	decisions, err := bootstrap.Explain(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return printDecisionsJSON(os.Stdout, decisions)
+	}
+	return printDecisionsTable(os.Stdout, decisions)
+}
+
+// printDecisionsTable writes one aligned row per pod decision
+func printDecisionsTable(w io.Writer, decisions []bootstrap.PodDecision) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "POD\tPHASE\tIP\tIP SOURCE\tMULTUS\tDECISION\tREASON")
+	for _, d := range decisions {
+		decision := "excluded"
+		if d.Included {
+			decision = "included"
+		}
+		reason := d.Reason
+		if d.Detail != "" {
+			reason = fmt.Sprintf("%s: %s", d.Reason, d.Detail)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Name, orDash(d.Phase), orDash(d.IP), orDash(d.IPSource), d.Multus, decision, reason)
+	}
+	return tw.Flush()
+}
+
+// printDecisionsJSON writes the pod decisions as an indented JSON array
+func printDecisionsJSON(w io.Writer, decisions []bootstrap.PodDecision) error {
+	if decisions == nil {
+		decisions = []bootstrap.PodDecision{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(decisions)
+}
+
+// orDash returns value, or "-" so an empty table cell stays visible
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,82 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+var testDecisions = []bootstrap.PodDecision{
+	{Name: "aeron-0", Phase: "Running", IP: "192.168.1.1", IPSource: bootstrap.IPSourceDefaultInterface, Endpoint: "192.168.1.1:8050",
+		Multus: bootstrap.MultusValid, Included: true, Reason: bootstrap.ExplainReasonSelected},
+	{Name: "aeron-1", Phase: "Running", Multus: bootstrap.SkipReasonMissingNetworkStatus, Reason: bootstrap.SkipReasonMissingNetworkStatus,
+		Detail: "has k8s.v1.cni.cncf.io/networks annotation but missing k8s.v1.cni.cncf.io/network-status annotation"},
+}
+
+func TestPrintDecisionsTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := printDecisionsTable(&out, testDecisions); err != nil {
+		t.Fatalf("printDecisionsTable() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %q", out.String())
+	}
+	expected := [][]string{
+		{"POD", "PHASE", "IP", "IP", "SOURCE", "MULTUS", "DECISION", "REASON"},
+		{"aeron-0", "Running", "192.168.1.1", "DefaultInterface", "Valid", "included", "Selected"},
+		{"aeron-1", "Running", "-", "-", "MissingNetworkStatus", "excluded", "MissingNetworkStatus:"},
+	}
+	for i, fields := range expected {
+		got := strings.Fields(lines[i])
+		if len(got) < len(fields) || strings.Join(got[:len(fields)], " ") != strings.Join(fields, " ") {
+			t.Errorf("Row %d = %q, expected it to start with %q", i, lines[i], fields)
+		}
+	}
+}
+
+func TestPrintDecisionsJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := printDecisionsJSON(&out, testDecisions); err != nil {
+		t.Fatalf("printDecisionsJSON() error = %v", err)
+	}
+
+	var decoded []bootstrap.PodDecision
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v\n%s", err, out.String())
+	}
+	if len(decoded) != 2 || !decoded[0].Included || decoded[1].Reason != bootstrap.SkipReasonMissingNetworkStatus {
+		t.Errorf("Decoded %+v, expected the test decisions", decoded)
+	}
+
+	out.Reset()
+	if err := printDecisionsJSON(&out, nil); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("printDecisionsJSON(nil) = (%q, %v), expected an empty array", out.String(), err)
+	}
+}
+
+func TestRunExplainRejectsUnknownOutput(t *testing.T) {
+	if err := runExplain([]string{"-output", "yaml"}); err == nil {
+		t.Errorf("Expected an error for an unknown output format")
+	}
+}
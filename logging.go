@@ -0,0 +1,62 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// getLogLevel returns the minimum log level from environment variable or default (info)
+func getLogLevel() slog.Level {
+	if levelStr := os.Getenv("AERON_MD_LOG_LEVEL"); levelStr != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(levelStr)); err == nil {
+			return level
+		}
+		fmt.Fprintf(os.Stderr, "Invalid AERON_MD_LOG_LEVEL value '%s', using default info\n", levelStr)
+	}
+	return slog.LevelInfo
+}
+
+// getLogFormat returns the log format (text or json) from environment variable or default (text)
+func getLogFormat() string {
+	if format := strings.ToLower(os.Getenv("AERON_MD_LOG_FORMAT")); format != "" {
+		if format == "text" || format == "json" {
+			return format
+		}
+		fmt.Fprintf(os.Stderr, "Invalid AERON_MD_LOG_FORMAT value '%s', using default text\n", format)
+	}
+	return "text"
+}
+
+// newLogger creates a logger writing in the given format at the given minimum level
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// setupLogging installs the logger configured by the environment as the default logger
+func setupLogging() {
+	slog.SetDefault(newLogger(os.Stderr, getLogFormat(), getLogLevel()))
+}
@@ -0,0 +1,82 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestGetLogLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected slog.Level
+	}{
+		{name: "default level when env not set", envValue: "", expected: slog.LevelInfo},
+		{name: "debug", envValue: "debug", expected: slog.LevelDebug},
+		{name: "upper case warn", envValue: "WARN", expected: slog.LevelWarn},
+		{name: "error", envValue: "error", expected: slog.LevelError},
+		{name: "invalid level uses default", envValue: "verbose", expected: slog.LevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_LOG_LEVEL", tt.envValue)
+			if result := getLogLevel(); result != tt.expected {
+				t.Errorf("getLogLevel() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetLogFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected string
+	}{
+		{name: "default format when env not set", envValue: "", expected: "text"},
+		{name: "json", envValue: "json", expected: "json"},
+		{name: "upper case JSON", envValue: "JSON", expected: "json"},
+		{name: "invalid format uses default", envValue: "xml", expected: "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AERON_MD_LOG_FORMAT", tt.envValue)
+			if result := getLogFormat(); result != tt.expected {
+				t.Errorf("getLogFormat() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewLoggerLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, "text", slog.LevelWarn)
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	output := buf.String()
+	if strings.Contains(output, "hidden") || !strings.Contains(output, "shown") {
+		t.Errorf("Expected only warn messages, got:\n%s", output)
+	}
+}
@@ -0,0 +1,162 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// errProbeFailed means the local media driver has been isolated from its peers for longer than the grace period
+var errProbeFailed = errors.New("probe failed")
+
+// probeThresholds decides when the local media driver counts as isolated
+type probeThresholds struct {
+	// minNeighbors is the fewest resolver neighbors required while the API reports any peers
+	minNeighbors int
+	// minPeerFraction is the fraction of the peers reported by the API that must be resolver neighbors
+	minPeerFraction float64
+	// gracePeriod is how long the driver may stay isolated before the probe fails
+	gracePeriod time.Duration
+}
+
+// runProbe compares the local media driver's resolver neighbors with the peers the API reports,
+// failing once the driver has been isolated for longer than the grace period
+func runProbe(args []string) error {
+	flags := flag.NewFlagSet("probe", flag.ContinueOnError)
+	aeronDir := flags.String("aeron-dir", getAeronDir(), "media driver directory holding "+bootstrap.CnCFileName)
+	stateFile := flags.String("state-file", getProbeStateFile(), "file remembering when the driver was first seen isolated")
+	var thresholds probeThresholds
+	flags.IntVar(&thresholds.minNeighbors, "min-neighbors", getProbeMinNeighbors(), "fewest resolver neighbors required while the API reports any peers")
+	flags.Float64Var(&thresholds.minPeerFraction, "min-peer-fraction", getProbeMinPeerFraction(), "fraction of the peers reported by the API that must be resolver neighbors")
+	flags.DurationVar(&thresholds.gracePeriod, "grace-period", getProbeGracePeriod(), "how long the driver may stay isolated before the probe fails")
+	apiTimeout := flags.Duration("api-timeout", getProbeAPITimeout(), "how long to ask the API for peers, within the probe's timeoutSeconds")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	status, err := readResolverStatus(*aeronDir)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errProbeFailed, err)
+	}
+
+	peers := countPeers(*apiTimeout)
+	isolated, required := isIsolated(status.Neighbors, peers, thresholds)
+	fmt.Printf("Resolver state=%s neighbors=%d peers=%d required=%d\n", status.State, status.Neighbors, peers, required)
+
+	since, err := trackIsolation(*stateFile, isolated, time.Now())
+	if err != nil {
+		slog.Warn("Failed to record probe state, the grace period restarts on every probe", "path", *stateFile, "error", err)
+	}
+	if !isolated {
+		return nil
+	}
+	if isolatedFor := time.Since(since); isolatedFor > thresholds.gracePeriod {
+		return fmt.Errorf("%w: driver has had %d of %d required resolver neighbors for %v", errProbeFailed,
+			status.Neighbors, required, isolatedFor.Round(time.Second))
+	}
+	slog.Info("Driver isolated, within grace period", "neighbors", status.Neighbors, "required", required, "since", since)
+	return nil
+}
+
+// countPeers returns how many other media driver pods the API reports, or -1 if the API can't be asked within timeout
+func countPeers(timeout time.Duration) int {
+	clientset, err := getInClusterConfig()
+	if err != nil {
+		slog.Warn("Can't compare with peers, checking the minimum neighbors only", "error", err)
+		return -1
+	}
+	namespace, err := getNamespace()
+	if err != nil {
+		slog.Warn("Can't compare with peers, checking the minimum neighbors only", "error", err)
+		return -1
+	}
+
+	// Bound the API calls, retries included, so the probe answers before the kubelet gives up on it
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	opts := probePeerOptions(optionsFromEnv(clientset, namespace, nil), timeout)
+	pods, _, err := bootstrap.DiscoverPods(ctx, opts)
+	if err != nil {
+		slog.Warn("Can't compare with peers, checking the minimum neighbors only", "error", err)
+		return -1
+	}
+
+	peers := 0
+	for _, pod := range pods {
+		if pod.Name != opts.PodName {
+			peers++
+		}
+	}
+	return peers
+}
+
+// probePeerOptions adapts the bootstrap options to count every peer within timeout,
+// as the neighbor limit would otherwise cap the peers reported
+func probePeerOptions(opts bootstrap.Options, timeout time.Duration) bootstrap.Options {
+	opts.MaxPods = 0
+	opts.APITimeout = min(opts.APITimeout, timeout)
+	return opts
+}
+
+// isIsolated returns whether a driver with the given resolver neighbors is isolated, and how many neighbors it needs
+// peers is the number of other media drivers the API reports, or negative if unknown
+func isIsolated(neighbors int64, peers int, thresholds probeThresholds) (bool, int) {
+	required := thresholds.minNeighbors
+	if peers >= 0 {
+		// A lone driver has nobody to gossip with, and the peers can't all be required if there are fewer than minNeighbors
+		required = max(required, int(math.Ceil(thresholds.minPeerFraction*float64(peers))))
+		required = min(required, peers)
+	}
+	return neighbors < int64(required), required
+}
+
+// trackIsolation records in stateFile when the driver was first seen isolated, clearing it once it is not,
+// and returns when the current isolation started
+func trackIsolation(stateFile string, isolated bool, now time.Time) (time.Time, error) {
+	if !isolated {
+		if err := os.Remove(stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return now, err
+		}
+		return now, nil
+	}
+
+	if data, err := os.ReadFile(stateFile); err == nil {
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64); err == nil {
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return now, err
+	}
+	return now, os.WriteFile(stateFile, []byte(strconv.FormatInt(now.Unix(), 10)+"\n"), 0644)
+}
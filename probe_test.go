@@ -0,0 +1,126 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+func TestIsIsolated(t *testing.T) {
+	tests := []struct {
+		name             string
+		neighbors        int64
+		peers            int
+		thresholds       probeThresholds
+		expectedIsolated bool
+		expectedRequired int
+	}{
+		{name: "one neighbor of two peers", neighbors: 1, peers: 2, thresholds: probeThresholds{minNeighbors: 1}, expectedRequired: 1},
+		{name: "no neighbors of two peers", neighbors: 0, peers: 2, thresholds: probeThresholds{minNeighbors: 1}, expectedIsolated: true, expectedRequired: 1},
+		{name: "lone driver", neighbors: 0, peers: 0, thresholds: probeThresholds{minNeighbors: 1}, expectedRequired: 0},
+		{name: "fraction of peers", neighbors: 2, peers: 4, thresholds: probeThresholds{minNeighbors: 1, minPeerFraction: 0.75}, expectedIsolated: true, expectedRequired: 3},
+		{name: "all peers", neighbors: 4, peers: 4, thresholds: probeThresholds{minPeerFraction: 1}, expectedRequired: 4},
+		{name: "minimum capped at peers", neighbors: 1, peers: 1, thresholds: probeThresholds{minNeighbors: 3}, expectedRequired: 1},
+		{name: "peers unknown", neighbors: 0, peers: -1, thresholds: probeThresholds{minNeighbors: 1, minPeerFraction: 1}, expectedIsolated: true, expectedRequired: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolated, required := isIsolated(tt.neighbors, tt.peers, tt.thresholds)
+			if isolated != tt.expectedIsolated || required != tt.expectedRequired {
+				t.Errorf("isIsolated() = (%v, %d), expected (%v, %d)", isolated, required, tt.expectedIsolated, tt.expectedRequired)
+			}
+		})
+	}
+}
+
+func TestTrackIsolation(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "probe", "state")
+	start := time.Unix(1700000000, 0)
+
+	since, err := trackIsolation(stateFile, true, start)
+	if err != nil || !since.Equal(start) {
+		t.Fatalf("First isolated trackIsolation() = (%v, %v), expected (%v, nil)", since, err, start)
+	}
+
+	// Later probes keep the time isolation started
+	since, err = trackIsolation(stateFile, true, start.Add(time.Minute))
+	if err != nil || !since.Equal(start) {
+		t.Errorf("Repeated trackIsolation() = (%v, %v), expected (%v, nil)", since, err, start)
+	}
+
+	// Recovering clears it, so the next isolation starts a new grace period
+	if _, err := trackIsolation(stateFile, false, start.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Recovered trackIsolation() error = %v", err)
+	}
+	restart := start.Add(3 * time.Minute)
+	since, err = trackIsolation(stateFile, true, restart)
+	if err != nil || !since.Equal(restart) {
+		t.Errorf("trackIsolation() after recovery = (%v, %v), expected (%v, nil)", since, err, restart)
+	}
+}
+
+func TestRunProbeMissingCnC(t *testing.T) {
+	err := runProbe([]string{"-aeron-dir", t.TempDir(), "-state-file", filepath.Join(t.TempDir(), "state")})
+	if !errors.Is(err, errProbeFailed) {
+		t.Errorf("runProbe() error = %v, expected %v", err, errProbeFailed)
+	}
+}
+
+func TestGetProbeThresholds(t *testing.T) {
+	t.Setenv("AERON_MD_PROBE_MIN_NEIGHBORS", "2")
+	t.Setenv("AERON_MD_PROBE_MIN_PEER_FRACTION", "1.5")
+	t.Setenv("AERON_MD_PROBE_GRACE_PERIOD", "30s")
+
+	if result := getProbeMinNeighbors(); result != 2 {
+		t.Errorf("getProbeMinNeighbors() = %d, expected 2", result)
+	}
+	if result := getProbeMinPeerFraction(); result != 0 {
+		t.Errorf("getProbeMinPeerFraction() = %v, expected the default 0 for an out of range value", result)
+	}
+	if result := getProbeGracePeriod(); result != 30*time.Second {
+		t.Errorf("getProbeGracePeriod() = %v, expected 30s", result)
+	}
+}
+
+func TestGetProbeAPITimeout(t *testing.T) {
+	t.Setenv("AERON_MD_PROBE_API_TIMEOUT", "")
+	if result := getProbeAPITimeout(); result >= time.Second {
+		t.Errorf("getProbeAPITimeout() = %v, expected less than the kubelet's default 1s probe timeout", result)
+	}
+	t.Setenv("AERON_MD_PROBE_API_TIMEOUT", "3s")
+	if result := getProbeAPITimeout(); result != 3*time.Second {
+		t.Errorf("getProbeAPITimeout() = %v, expected 3s", result)
+	}
+}
+
+func TestProbePeerOptions(t *testing.T) {
+	opts := bootstrap.DefaultOptions()
+	opts.MaxPods = 3
+	opts = probePeerOptions(opts, 500*time.Millisecond)
+	if opts.MaxPods != 0 {
+		t.Errorf("MaxPods = %d, expected 0 so every peer is counted", opts.MaxPods)
+	}
+	if opts.APITimeout != 500*time.Millisecond {
+		t.Errorf("APITimeout = %v, expected the probe's 500ms", opts.APITimeout)
+	}
+}
@@ -0,0 +1,90 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// errVerifyFailed means the local media driver's resolver did not reach the expected neighbors
+var errVerifyFailed = errors.New("bootstrap verification failed")
+
+// verifyRetryInterval is how often verify re-reads the counters while waiting for the expected neighbors
+const verifyRetryInterval = time.Second
+
+// runVerify checks the local media driver's resolver counters from its CnC file, replacing AeronStat in CI
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	aeronDir := flags.String("aeron-dir", getAeronDir(), "media driver directory holding "+bootstrap.CnCFileName)
+	expected := flags.Int("expected-neighbors", -1, "exact number of resolver neighbors required, -1 requires at least one")
+	timeout := flags.Duration("timeout", 0, "keep checking until the check passes or this long has passed")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	deadline := time.Now().Add(*timeout)
+	for {
+		status, err := readResolverStatus(*aeronDir)
+		if err == nil {
+			fmt.Printf("Resolver state=%s address=%s neighbors=%d cacheEntries=%d\n",
+				status.State, status.BoundAddress, status.Neighbors, status.CacheEntries)
+			err = checkResolverStatus(status, *expected)
+		}
+		if err == nil {
+			slog.Info("Bootstrap verified", "aeronDir", *aeronDir, "neighbors", status.Neighbors, "state", status.State)
+			return nil
+		}
+		if time.Now().Add(verifyRetryInterval).After(deadline) {
+			return fmt.Errorf("%w: %w", errVerifyFailed, err)
+		}
+		slog.Debug("Bootstrap not verified yet, retrying", "aeronDir", *aeronDir, "error", err)
+		time.Sleep(verifyRetryInterval)
+	}
+}
+
+// readResolverStatus reads the resolver counters of the media driver in aeronDir
+func readResolverStatus(aeronDir string) (bootstrap.ResolverStatus, error) {
+	counters, err := bootstrap.ReadCounters(aeronDir)
+	if err != nil {
+		return bootstrap.ResolverStatus{}, err
+	}
+	return bootstrap.GetResolverStatus(counters), nil
+}
+
+// checkResolverStatus checks the resolver is bound with the expected number of neighbors, or at least one if expected is negative
+func checkResolverStatus(status bootstrap.ResolverStatus, expected int) error {
+	switch {
+	case status.State == bootstrap.ResolverStateAbsent:
+		return errors.New("media driver has no name resolver, is the bootstrap file loaded?")
+	case status.State != bootstrap.ResolverStateBound:
+		return fmt.Errorf("resolver is %s", status.State)
+	case expected < 0 && status.Neighbors < 1:
+		return errors.New("resolver has no neighbors")
+	case expected >= 0 && status.Neighbors != int64(expected):
+		return fmt.Errorf("resolver has %d neighbors, expected %d", status.Neighbors, expected)
+	}
+	return nil
+}
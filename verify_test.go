@@ -0,0 +1,75 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+func TestCheckResolverStatus(t *testing.T) {
+	bound := func(neighbors int64) bootstrap.ResolverStatus {
+		return bootstrap.ResolverStatus{State: bootstrap.ResolverStateBound, BoundAddress: "10.0.0.1:8050", Neighbors: neighbors}
+	}
+
+	tests := []struct {
+		name      string
+		status    bootstrap.ResolverStatus
+		expected  int
+		expectErr bool
+	}{
+		{name: "expected neighbors", status: bound(2), expected: 2},
+		{name: "too few neighbors", status: bound(1), expected: 2, expectErr: true},
+		{name: "too many neighbors", status: bound(3), expected: 2, expectErr: true},
+		{name: "any neighbors", status: bound(1), expected: -1},
+		{name: "no neighbors", status: bound(0), expected: -1, expectErr: true},
+		{name: "zero neighbors expected", status: bound(0), expected: 0},
+		{name: "no resolver", status: bootstrap.ResolverStatus{State: bootstrap.ResolverStateAbsent}, expected: -1, expectErr: true},
+		{name: "resolver not bound", status: bootstrap.ResolverStatus{State: bootstrap.ResolverStateUnbound, Neighbors: 2}, expected: 2, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkResolverStatus(tt.status, tt.expected)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("checkResolverStatus() error = %v, expected error = %v", err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestRunVerifyMissingCnC(t *testing.T) {
+	err := runVerify([]string{"-aeron-dir", t.TempDir(), "-expected-neighbors", "2"})
+	if !errors.Is(err, errVerifyFailed) || !errors.Is(err, bootstrap.ErrCnCNotReady) {
+		t.Errorf("runVerify() error = %v, expected %v wrapping %v", err, errVerifyFailed, bootstrap.ErrCnCNotReady)
+	}
+}
+
+func TestDispatchUnknownCommand(t *testing.T) {
+	if err := dispatch([]string{"frobnicate"}); err == nil {
+		t.Errorf("Expected an error for an unknown command")
+	}
+}
+
+func TestGetAeronDir(t *testing.T) {
+	t.Setenv("AERON_DIR", "/dev/shm/aeron-custom")
+	if result := getAeronDir(); result != "/dev/shm/aeron-custom" {
+		t.Errorf("getAeronDir() = %s, expected /dev/shm/aeron-custom", result)
+	}
+}
@@ -0,0 +1,95 @@
+// aeron-k8s-bootstrap - Kubernetes startup shim for Aeron media drivers
+// Copyright (C) 2025 JMIPS Ltd.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"jmips.co.uk/aeron-k8s-bootstrap/bootstrap"
+)
+
+// runWebhook serves the admission webhooks until interrupted, injecting the bootstrap into media driver pods at /mutate
+// and checking pods against the Aeron network at /validate
+func runWebhook(args []string) error {
+	flags := flag.NewFlagSet("webhook", flag.ContinueOnError)
+	addr := flags.String("addr", ":8443", "listen address of the webhook")
+	tlsCert := flags.String("tls-cert", "/etc/webhook/tls/tls.crt", "TLS certificate the API server trusts")
+	tlsKey := flags.String("tls-key", "/etc/webhook/tls/tls.key", "TLS private key")
+	enforce := flags.Bool("enforce", false, "reject pods inconsistent with the Aeron network, rather than warning")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	config := bootstrap.InjectionConfig{
+		Image:           getInjectImage(),
+		ImagePullPolicy: getInjectPullPolicy(),
+		Env:             getInjectEnv(),
+	}
+	dynamicClient, err := getInClusterDynamicClient()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	validation := bootstrap.NetworkValidation{
+		Dynamic:       dynamicClient,
+		LabelSelector: getLabelSelector(),
+		SecondaryInterface: bootstrap.SecondaryInterface{
+			NetworkName:   os.Getenv("AERON_MD_SECONDARY_INTERFACE_NETWORK_NAME"),
+			InterfaceName: os.Getenv("AERON_MD_SECONDARY_INTERFACE_NAME"),
+		},
+		Enforce:    *enforce,
+		APITimeout: getAPITimeout(),
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/mutate", bootstrap.InjectionHandler(config))
+	mux.Handle("/validate", bootstrap.ValidationHandler(validation))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Serving admission webhooks", "addr", *addr, "image", config.Image, "enforce", *enforce)
+	if err := server.ListenAndServeTLS(*tlsCert, *tlsKey); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("webhook server failed: %w", err)
+	}
+	return nil
+}